@@ -0,0 +1,78 @@
+package txtar
+
+import (
+	"iter"
+	"maps"
+	"slices"
+)
+
+// Clone returns a deep copy of the archive, including its configuration (e.g. [WithVerbatim]
+// and [NamePolicy]), that can be modified without affecting the original and vice versa.
+//
+// Cloning a nil archive returns nil.
+func (a *Archive) Clone() *Archive {
+	if a == nil {
+		return nil
+	}
+
+	c := *a
+	c.files = slices.Clone(a.files)
+	c.index = maps.Clone(a.index)
+
+	return &c
+}
+
+// Snapshot is a read-only view of an [Archive] as it was at the moment [Archive.Snapshot]
+// was called.
+//
+// It exposes only the methods that read from the archive, so can be shared freely, e.g. as
+// a test fixture, without any risk of it being modified. Use [Snapshot.Clone] to get a
+// modifiable copy.
+//
+// A Snapshot is safe for concurrent use, and the zero value is an empty archive.
+type Snapshot struct {
+	archive *Archive // Private copy of the archive, never modified
+}
+
+// Snapshot returns a read-only [Snapshot] of the archive's current contents.
+//
+// Later changes to the archive do not affect the snapshot.
+func (a *Archive) Snapshot() Snapshot {
+	return Snapshot{archive: a.Clone()}
+}
+
+// Comment returns the archive comment, see [Archive.Comment].
+func (s Snapshot) Comment() string {
+	return s.archive.Comment()
+}
+
+// Has returns whether the archive contains a file with the given name, see [Archive.Has].
+func (s Snapshot) Has(name string) bool {
+	return s.archive.Has(name)
+}
+
+// Read returns the contents of the named file, see [Archive.Read].
+func (s Snapshot) Read(name string) (value string, ok bool) {
+	return s.archive.Read(name)
+}
+
+// Files returns an iterator over the archive's filenames and contents, see [Archive.Files].
+func (s Snapshot) Files() iter.Seq2[string, string] {
+	return s.archive.Files()
+}
+
+// Size returns the number of files in the archive, see [Archive.Size].
+func (s Snapshot) Size() int {
+	return s.archive.Size()
+}
+
+// Clone returns a modifiable deep copy of the archive, see [Archive.Clone].
+//
+// Cloning the zero Snapshot returns a new empty archive.
+func (s Snapshot) Clone() *Archive {
+	if s.archive == nil {
+		return &Archive{}
+	}
+
+	return s.archive.Clone()
+}
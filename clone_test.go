@@ -0,0 +1,101 @@
+package txtar_test
+
+import (
+	"maps"
+	"sync"
+	"testing"
+
+	"go.followtheprocess.codes/test"
+	"go.followtheprocess.codes/txtar"
+)
+
+func TestClone(t *testing.T) {
+	original, err := txtar.New(
+		txtar.WithVerbatim(),
+		txtar.WithNamePolicy(txtar.NameClean),
+		txtar.WithComment("comment"),
+		txtar.WithFile("a.txt", "a"),
+		txtar.WithFile("b.txt", "b"),
+	)
+	test.Ok(t, err)
+
+	before := original.String()
+
+	clone := original.Clone()
+	test.True(t, txtar.Equal(original, clone))
+
+	// Modifying the clone must leave the original alone
+	test.Ok(t, clone.Write("a.txt", "changed"))
+	test.Ok(t, clone.Write("c.txt", "new"))
+	clone.Delete("b.txt")
+	test.Ok(t, txtar.WithComment("changed")(clone))
+
+	test.Diff(t, original.String(), before)
+	test.False(t, original.Has("c.txt"))
+
+	// And the configuration comes along with it
+	test.Ok(t, clone.Write("dir\\d.txt", "  verbatim  "))
+	contents, ok := clone.Read("dir/d.txt")
+	test.True(t, ok)
+	test.Equal(t, contents, "  verbatim  \n")
+
+	// And the other way around
+	original.Delete("a.txt")
+
+	contents, ok = clone.Read("a.txt")
+	test.True(t, ok)
+	test.Equal(t, contents, "changed\n")
+
+	var missing *txtar.Archive
+	test.True(t, missing.Clone() == nil)
+}
+
+func TestSnapshot(t *testing.T) {
+	archive, err := txtar.New(
+		txtar.WithComment("comment"),
+		txtar.WithFile("a.txt", "a"),
+		txtar.WithFile("b.txt", "b"),
+	)
+	test.Ok(t, err)
+
+	snapshot := archive.Snapshot()
+
+	// Later changes to the archive are not seen by the snapshot
+	test.Ok(t, archive.Write("a.txt", "changed"))
+	test.Ok(t, archive.Write("c.txt", "c"))
+
+	test.Equal(t, snapshot.Comment(), "comment")
+	test.Equal(t, snapshot.Size(), 2)
+	test.True(t, snapshot.Has("b.txt"))
+	test.False(t, snapshot.Has("c.txt"))
+
+	contents, ok := snapshot.Read("a.txt")
+	test.True(t, ok)
+	test.Equal(t, contents, "a\n")
+
+	files := maps.Collect(snapshot.Files())
+	test.EqualFunc(t, files, map[string]string{"a.txt": "a\n", "b.txt": "b\n"}, maps.Equal)
+
+	// Nor are changes to a clone of it
+	clone := snapshot.Clone()
+	clone.Delete("a.txt")
+	test.True(t, snapshot.Has("a.txt"))
+
+	// Safe to share between goroutines
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Go(func() {
+			for name := range snapshot.Files() {
+				test.True(t, snapshot.Has(name))
+			}
+		})
+	}
+
+	wg.Wait()
+
+	// The zero value is empty
+	var empty txtar.Snapshot
+	test.Equal(t, empty.Size(), 0)
+	test.False(t, empty.Has("a.txt"))
+	test.Ok(t, empty.Clone().Write("a.txt", "a"))
+}
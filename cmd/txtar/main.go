@@ -0,0 +1,210 @@
+// Command txtar is a tool for working with txtar archives.
+//
+// Usage:
+//
+//	txtar fmt [flags] [path ...]
+//
+// The fmt command formats archives into the canonical form produced by [txtar.Format],
+// much like gofmt does for Go source. Given a file it formats that file, given a directory
+// it formats every .txtar file beneath it, and given no paths at all it formats standard
+// input. By default the formatted archives are written to standard output.
+//
+// The flags are:
+//
+//	-d
+//		Print a diff of the changes rather than the formatted archive, in the
+//		same form as gofmt -d.
+//	-l
+//		List the files whose formatting differs from the canonical form.
+//	-w
+//		Write the formatted archive back to the file it came from.
+//	-sort
+//		Sort the files in each archive by name.
+//
+// Archives are parsed leniently, so anything the original txtar package can read is
+// formatted. Any file that cannot be read or written is reported and left alone, and
+// txtar exits with status 2 once every other file has been processed.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"go.followtheprocess.codes/txtar"
+)
+
+const usage = `txtar is a tool for working with txtar archives.
+
+Usage:
+
+	txtar fmt [flags] [path ...]
+
+Commands:
+
+	fmt	format archives into their canonical form
+
+Run 'txtar fmt -h' for details of its flags.
+`
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+}
+
+// run is the real entry point, separated from main so it can be tested.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return errors.New("txtar: no command given")
+	}
+
+	switch args[0] {
+	case "fmt":
+		return runFmt(args[1:], stdin, stdout, stderr)
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(stdout, usage)
+		return nil
+	default:
+		return fmt.Errorf("txtar: unknown command %q, run 'txtar help' for usage", args[0])
+	}
+}
+
+// formatter holds the configuration for a single run of the fmt command.
+type formatter struct {
+	stdout  io.Writer          // Where formatted archives, diffs and file lists are written
+	options []txtar.DumpOption // Options passed to txtar.Format
+	list    bool               // -l: list files whose formatting differs
+	write   bool               // -w: write the result back to the source file
+	diff    bool               // -d: print diffs
+}
+
+// runFmt implements the fmt command.
+func runFmt(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	f := &formatter{stdout: stdout}
+
+	flags := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.BoolVar(&f.diff, "d", false, "print a diff of the changes rather than the formatted archive")
+	flags.BoolVar(&f.list, "l", false, "list the files whose formatting differs from the canonical form")
+	flags.BoolVar(&f.write, "w", false, "write the formatted archive back to the file it came from")
+	sorted := flags.Bool("sort", false, "sort the files in each archive by name")
+
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: txtar fmt [flags] [path ...]")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+
+		return err
+	}
+
+	if *sorted {
+		f.options = append(f.options, txtar.Sorted())
+	}
+
+	if flags.NArg() == 0 {
+		if f.write {
+			return errors.New("txtar fmt: cannot use -w with standard input")
+		}
+
+		src, err := io.ReadAll(stdin)
+		if err != nil {
+			return err
+		}
+
+		return f.format("<standard input>", src)
+	}
+
+	var errs error
+
+	for _, path := range flags.Args() {
+		info, err := os.Stat(path)
+		if err != nil {
+			errs = errors.Join(errs, err)
+			continue
+		}
+
+		if !info.IsDir() {
+			errs = errors.Join(errs, f.formatFile(path))
+			continue
+		}
+
+		err = filepath.WalkDir(path, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				errs = errors.Join(errs, err)
+				return nil
+			}
+
+			if entry.Type().IsRegular() && filepath.Ext(path) == ".txtar" {
+				errs = errors.Join(errs, f.formatFile(path))
+			}
+
+			return nil
+		})
+		errs = errors.Join(errs, err)
+	}
+
+	return errs
+}
+
+// formatFile formats the archive in the file at path.
+func (f *formatter) formatFile(path string) error {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	return f.format(path, src)
+}
+
+// format formats src, read from path, and does whatever the flags ask with the result.
+func (f *formatter) format(path string, src []byte) error {
+	formatted, err := txtar.Format(src, f.options...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	if !f.list && !f.write && !f.diff {
+		_, err := f.stdout.Write(formatted)
+		return err
+	}
+
+	if bytes.Equal(src, formatted) {
+		return nil
+	}
+
+	if f.list {
+		fmt.Fprintln(f.stdout, path)
+	}
+
+	if f.write {
+		// The file already exists so the permissions are left as they are
+		if err := os.WriteFile(path, formatted, 0o644); err != nil {
+			return err
+		}
+	}
+
+	if f.diff {
+		// Like gofmt -d, these aren't files in git so don't pretend with a/ and b/ prefixes
+		d := txtar.FileDiff{Old: string(src), New: string(formatted)}
+		header := fmt.Sprintf("diff %[1]s.orig %[1]s\n--- %[1]s.orig\n+++ %[1]s\n", path)
+
+		if _, err := io.WriteString(f.stdout, header+d.Hunks()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.followtheprocess.codes/test"
+)
+
+const (
+	formatted = "comment\n\n-- a.txt --\na\n"
+	messy     = "comment\r\n--  a.txt  --\r\na   \r\n\r\n"
+)
+
+func TestFmtStdin(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	test.Ok(t, run([]string{"fmt"}, strings.NewReader(messy), stdout, &bytes.Buffer{}))
+	test.Diff(t, stdout.String(), formatted)
+
+	stdout.Reset()
+	test.Ok(t, run([]string{"fmt", "-sort"}, strings.NewReader("-- b --\n-- a --\n"), stdout, &bytes.Buffer{}))
+	test.Diff(t, stdout.String(), "-- a --\n-- b --\n")
+
+	test.Err(t, run([]string{"fmt", "-w"}, strings.NewReader(messy), &bytes.Buffer{}, &bytes.Buffer{}))
+}
+
+func TestFmtList(t *testing.T) {
+	dir := fixtures(t)
+
+	stdout := &bytes.Buffer{}
+	test.Ok(t, run([]string{"fmt", "-l", dir}, nil, stdout, &bytes.Buffer{}))
+
+	want := filepath.Join(dir, "messy.txtar") + "\n" + filepath.Join(dir, "nested", "deep", "messy.txtar") + "\n"
+	test.Diff(t, stdout.String(), want)
+
+	// Nothing is modified
+	contents, err := os.ReadFile(filepath.Join(dir, "messy.txtar"))
+	test.Ok(t, err)
+	test.Equal(t, string(contents), messy)
+}
+
+func TestFmtWrite(t *testing.T) {
+	dir := fixtures(t)
+
+	stdout := &bytes.Buffer{}
+	test.Ok(t, run([]string{"fmt", "-w", dir}, nil, stdout, &bytes.Buffer{}))
+	test.Equal(t, stdout.String(), "")
+
+	for _, name := range []string{"messy.txtar", "formatted.txtar", filepath.Join("nested", "deep", "messy.txtar")} {
+		contents, err := os.ReadFile(filepath.Join(dir, name))
+		test.Ok(t, err)
+		test.Diff(t, string(contents), formatted)
+	}
+
+	// Only .txtar files are touched
+	contents, err := os.ReadFile(filepath.Join(dir, "other.txt"))
+	test.Ok(t, err)
+	test.Equal(t, string(contents), messy)
+
+	// Running again finds nothing to do
+	stdout.Reset()
+	test.Ok(t, run([]string{"fmt", "-l", dir}, nil, stdout, &bytes.Buffer{}))
+	test.Equal(t, stdout.String(), "")
+}
+
+func TestFmtDiff(t *testing.T) {
+	dir := fixtures(t)
+	path := filepath.Join(dir, "messy.txtar")
+
+	stdout := &bytes.Buffer{}
+	test.Ok(t, run([]string{"fmt", "-d", path}, nil, stdout, &bytes.Buffer{}))
+
+	// Plain headers like gofmt -d, even for absolute paths
+	header := "diff " + path + ".orig " + path + "\n--- " + path + ".orig\n+++ " + path + "\n@@ "
+	test.True(t, filepath.IsAbs(path))
+	test.True(t, strings.HasPrefix(stdout.String(), header), test.Context("Wrong header:\n%s", stdout.String()))
+	test.True(t, strings.Contains(stdout.String(), "\n+-- a.txt --\n"))
+
+	// Nothing for a formatted file
+	stdout.Reset()
+	test.Ok(t, run([]string{"fmt", "-d", filepath.Join(dir, "formatted.txtar")}, nil, stdout, &bytes.Buffer{}))
+	test.Equal(t, stdout.String(), "")
+}
+
+func TestFmtLenient(t *testing.T) {
+	tests := []struct {
+		name string // Name of the test case
+		src  string // Archive to format
+		want string // Expected output
+	}{
+		{name: "comment only", src: "just comment  \r\n", want: "just comment\n"},
+		{name: "duplicates", src: "-- a --\n-- a --\n", want: "-- a --\n-- a --\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout := &bytes.Buffer{}
+			test.Ok(t, run([]string{"fmt"}, strings.NewReader(tt.src), stdout, &bytes.Buffer{}))
+			test.Diff(t, stdout.String(), tt.want)
+		})
+	}
+}
+
+func TestFmtErrors(t *testing.T) {
+	dir := fixtures(t)
+	missing := filepath.Join(dir, "missing.txtar")
+
+	stdout := &bytes.Buffer{}
+	err := run([]string{"fmt", "-w", dir, missing}, nil, stdout, &bytes.Buffer{})
+	test.Err(t, err)
+	test.True(t, strings.Contains(err.Error(), missing))
+
+	// The others are still formatted
+	contents, err := os.ReadFile(filepath.Join(dir, "messy.txtar"))
+	test.Ok(t, err)
+	test.Diff(t, string(contents), formatted)
+
+	test.Err(t, run(nil, nil, &bytes.Buffer{}, &bytes.Buffer{}))
+	test.Err(t, run([]string{"nope"}, nil, &bytes.Buffer{}, &bytes.Buffer{}))
+	test.Ok(t, run([]string{"help"}, nil, &bytes.Buffer{}, &bytes.Buffer{}))
+}
+
+// fixtures creates a directory tree of archives, some needing formatting, returning
+// the path to its root.
+func fixtures(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"messy.txtar":     messy,
+		"formatted.txtar": formatted,
+		"other.txt":       messy,
+		filepath.Join("nested", "deep", "messy.txtar"): messy,
+	}
+
+	for name, contents := range files {
+		path := filepath.Join(dir, name)
+		test.Ok(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		test.Ok(t, os.WriteFile(path, []byte(contents), 0o644))
+	}
+
+	return dir
+}
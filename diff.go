@@ -0,0 +1,239 @@
+package txtar
+
+import (
+	"cmp"
+	"iter"
+	"slices"
+	"strings"
+
+	"go.followtheprocess.codes/diff"
+)
+
+// ArchiveDiff is the structured difference between two archives, as returned by [Diff].
+type ArchiveDiff struct {
+	OldComment string     // The comment of the old archive
+	NewComment string     // The comment of the new archive
+	Added      []FileDiff // Files only in the new archive
+	Removed    []FileDiff // Files only in the old archive
+	Renamed    []FileDiff // Files with identical contents that have changed name
+	Modified   []FileDiff // Files in both archives with different contents
+}
+
+// FileDiff describes a change to a single file in an [ArchiveDiff].
+//
+// OldName is empty for an added file and NewName is empty for a removed one, likewise
+// for the contents.
+type FileDiff struct {
+	OldName string // Name of the file in the old archive
+	NewName string // Name of the file in the new archive
+	Old     string // Contents of the file in the old archive
+	New     string // Contents of the file in the new archive
+}
+
+// Diff compares two archives, describing the changes needed to turn a into b.
+//
+// Files are matched by name, then any file removed from a whose contents exactly match
+// a file added in b is reported as renamed rather than removed and added. Empty files
+// are never considered renamed as any two are identical.
+//
+// A nil archive is treated as empty. Only the first of any duplicate files (see
+// [DuplicateKeepAll]) is compared, as that is the one returned by [Archive.Read].
+func Diff(a, b *Archive) *ArchiveDiff {
+	d := &ArchiveDiff{
+		OldComment: a.Comment(),
+		NewComment: b.Comment(),
+	}
+
+	for name, contents := range a.unique() {
+		newContents, ok := b.Read(name)
+		switch {
+		case !ok:
+			d.Removed = append(d.Removed, FileDiff{OldName: name, Old: contents})
+		case newContents != contents:
+			d.Modified = append(d.Modified, FileDiff{OldName: name, NewName: name, Old: contents, New: newContents})
+		}
+	}
+
+	for name, contents := range b.unique() {
+		if !a.Has(name) {
+			d.Added = append(d.Added, FileDiff{NewName: name, New: contents})
+		}
+	}
+
+	// Pair up removed and added files with identical contents, first come first served
+	d.Removed = slices.DeleteFunc(d.Removed, func(removed FileDiff) bool {
+		if removed.Old == "" {
+			return false
+		}
+
+		i := slices.IndexFunc(d.Added, func(added FileDiff) bool { return added.New == removed.Old })
+		if i < 0 {
+			return false
+		}
+
+		d.Renamed = append(d.Renamed, FileDiff{
+			OldName: removed.OldName,
+			NewName: d.Added[i].NewName,
+			Old:     removed.Old,
+			New:     removed.Old,
+		})
+		d.Added = slices.Delete(d.Added, i, i+1)
+
+		return true
+	})
+
+	return d
+}
+
+// Equal reports whether the two archives compared were the same, i.e. there
+// are no differences at all.
+func (d *ArchiveDiff) Equal() bool {
+	return !d.CommentChanged() &&
+		len(d.Added) == 0 &&
+		len(d.Removed) == 0 &&
+		len(d.Renamed) == 0 &&
+		len(d.Modified) == 0
+}
+
+// CommentChanged reports whether the archive comment differs.
+func (d *ArchiveDiff) CommentChanged() bool {
+	return d.OldComment != d.NewComment
+}
+
+// Files returns every file change in the diff, sorted by name (the new name for
+// renamed files).
+func (d *ArchiveDiff) Files() []FileDiff {
+	files := slices.Concat(d.Removed, d.Added, d.Renamed, d.Modified)
+	slices.SortFunc(files, func(a, b FileDiff) int {
+		return cmp.Compare(a.name(), b.name())
+	})
+
+	return files
+}
+
+// String renders the whole diff as a text report, in the unified format produced
+// by git diff: a diff of the comment (if it has changed) followed by [FileDiff.Unified]
+// for each file in [ArchiveDiff.Files].
+//
+// The comment has no equivalent in git so is shown as a diff of a pseudo file named "comment",
+// without the "diff --git" line so as not to be mistaken for a real file.
+//
+// String returns "" if there are no differences.
+func (d *ArchiveDiff) String() string {
+	s := &strings.Builder{}
+
+	if d.CommentChanged() {
+		s.WriteString("--- comment\n+++ comment\n")
+		writeHunks(s, d.OldComment, d.NewComment)
+	}
+
+	for _, file := range d.Files() {
+		s.WriteString(file.Unified())
+	}
+
+	return s.String()
+}
+
+// Unified renders the change to the file in the unified diff format produced by
+// git diff, including the "diff --git" header line.
+//
+// As in git, added and removed files are marked with a "new file mode" or
+// "deleted file mode" line and use /dev/null for the missing side, and a renamed
+// file is shown with "rename from" and "rename to" lines and no hunks.
+func (f FileDiff) Unified() string {
+	s := &strings.Builder{}
+
+	oldName, newName := f.OldName, f.NewName
+	switch {
+	case oldName == "":
+		oldName = newName
+	case newName == "":
+		newName = oldName
+	}
+
+	s.WriteString("diff --git a/" + oldName + " b/" + newName + "\n")
+
+	oldPath, newPath := "a/"+oldName, "b/"+newName
+
+	switch {
+	case f.OldName == "":
+		s.WriteString("new file mode 100644\n")
+
+		oldPath = "/dev/null"
+	case f.NewName == "":
+		s.WriteString("deleted file mode 100644\n")
+
+		newPath = "/dev/null"
+	case f.OldName != f.NewName:
+		s.WriteString("similarity index 100%\n")
+		s.WriteString("rename from " + f.OldName + "\n")
+		s.WriteString("rename to " + f.NewName + "\n")
+	}
+
+	// Like git, an empty file being added or removed has no hunks at all
+	if f.Old != f.New {
+		s.WriteString("--- " + oldPath + "\n")
+		s.WriteString("+++ " + newPath + "\n")
+		writeHunks(s, f.Old, f.New)
+	}
+
+	return s.String()
+}
+
+// Hunks renders just the unified diff hunks for the change to the file, without any
+// file headers, for callers that need headers of their own (e.g. for files on disk
+// rather than in an archive). It returns "" if the contents are unchanged.
+func (f FileDiff) Hunks() string {
+	s := &strings.Builder{}
+	writeHunks(s, f.Old, f.New)
+
+	return s.String()
+}
+
+// name returns the name to sort a file change by.
+func (f FileDiff) name() string {
+	return cmp.Or(f.NewName, f.OldName)
+}
+
+// writeHunks writes the unified diff hunks (without file headers) turning before
+// into after to s.
+func writeHunks(s *strings.Builder, before, after string) {
+	for _, line := range diff.New("before", []byte(before), "after", []byte(after)).Lines() {
+		switch line.Kind {
+		case diff.KindHeader:
+			// We write our own file headers, only the hunk headers are wanted
+			if strings.HasPrefix(string(line.Content), "@@") {
+				s.Write(line.Content)
+			}
+		case diff.KindRemoved:
+			s.WriteByte('-')
+			s.Write(line.Content)
+		case diff.KindAdded:
+			s.WriteByte('+')
+			s.Write(line.Content)
+		case diff.KindContext:
+			s.WriteByte(' ')
+			s.Write(line.Content)
+		}
+	}
+}
+
+// unique returns an iterator over the archive's files, skipping any duplicates
+// (see [DuplicateKeepAll]) after the first.
+func (a *Archive) unique() iter.Seq2[string, string] {
+	return func(yield func(string, string) bool) {
+		if a == nil {
+			return
+		}
+
+		for i, file := range a.files {
+			if first, _ := a.find(file.name); first != i {
+				continue
+			}
+
+			if !yield(file.name, file.contents) {
+				return
+			}
+		}
+	}
+}
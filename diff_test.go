@@ -0,0 +1,149 @@
+package txtar_test
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"go.followtheprocess.codes/test"
+	"go.followtheprocess.codes/txtar"
+)
+
+func TestDiff(t *testing.T) {
+	before, err := txtar.New(
+		txtar.WithComment("Old comment"),
+		txtar.WithFile("same.txt", "unchanged"),
+		txtar.WithFile("modified.txt", "one\ntwo\nthree\n"),
+		txtar.WithFile("removed.txt", "going away"),
+		txtar.WithFile("old/name.txt", "moving house"),
+		txtar.WithFile("empty.txt", ""),
+	)
+	test.Ok(t, err)
+
+	after, err := txtar.New(
+		txtar.WithComment("New comment"),
+		txtar.WithFile("new/name.txt", "moving house"),
+		txtar.WithFile("same.txt", "unchanged"),
+		txtar.WithFile("modified.txt", "one\n2\nthree\n"),
+		txtar.WithFile("added.txt", "brand new"),
+		txtar.WithFile("another_empty.txt", ""),
+	)
+	test.Ok(t, err)
+
+	d := txtar.Diff(before, after)
+
+	test.False(t, d.Equal())
+	test.True(t, d.CommentChanged())
+	test.Equal(t, d.OldComment, "Old comment")
+	test.Equal(t, d.NewComment, "New comment")
+
+	test.EqualFunc(t, d.Added, []txtar.FileDiff{
+		{NewName: "added.txt", New: "brand new\n"},
+		{NewName: "another_empty.txt"},
+	}, slices.Equal)
+
+	test.EqualFunc(t, d.Removed, []txtar.FileDiff{
+		{OldName: "removed.txt", Old: "going away\n"},
+		{OldName: "empty.txt"},
+	}, slices.Equal)
+
+	test.EqualFunc(t, d.Renamed, []txtar.FileDiff{
+		{OldName: "old/name.txt", NewName: "new/name.txt", Old: "moving house\n", New: "moving house\n"},
+	}, slices.Equal)
+
+	test.EqualFunc(t, d.Modified, []txtar.FileDiff{
+		{OldName: "modified.txt", NewName: "modified.txt", Old: "one\ntwo\nthree\n", New: "one\n2\nthree\n"},
+	}, slices.Equal)
+
+	want := `--- comment
++++ comment
+@@ -1,1 +1,1 @@
+-Old comment
+\ No newline at end of file
++New comment
+\ No newline at end of file
+diff --git a/added.txt b/added.txt
+new file mode 100644
+--- /dev/null
++++ b/added.txt
+@@ -0,0 +1,1 @@
++brand new
+diff --git a/another_empty.txt b/another_empty.txt
+new file mode 100644
+diff --git a/empty.txt b/empty.txt
+deleted file mode 100644
+diff --git a/modified.txt b/modified.txt
+--- a/modified.txt
++++ b/modified.txt
+@@ -1,3 +1,3 @@
+ one
+-two
++2
+ three
+diff --git a/old/name.txt b/new/name.txt
+similarity index 100%
+rename from old/name.txt
+rename to new/name.txt
+diff --git a/removed.txt b/removed.txt
+deleted file mode 100644
+--- a/removed.txt
++++ /dev/null
+@@ -1,1 +0,0 @@
+-going away
+`
+	test.Diff(t, d.String(), want)
+}
+
+func TestDiffEqual(t *testing.T) {
+	archive, err := txtar.New(
+		txtar.WithComment("comment"),
+		txtar.WithFile("a.txt", "a"),
+	)
+	test.Ok(t, err)
+
+	d := txtar.Diff(archive, archive)
+	test.True(t, d.Equal())
+	test.Equal(t, d.String(), "")
+
+	// Nil is just empty
+	test.True(t, txtar.Diff(nil, nil).Equal())
+
+	d = txtar.Diff(nil, archive)
+	test.Equal(t, len(d.Added), 1)
+	test.True(t, d.CommentChanged())
+
+	// Equal and Diff should always agree
+	other, err := txtar.New(txtar.WithComment("comment"), txtar.WithFile("a.txt", "b"))
+	test.Ok(t, err)
+	test.Equal(t, txtar.Diff(archive, other).Equal(), txtar.Equal(archive, other))
+}
+
+func TestFileDiffUnified(t *testing.T) {
+	file := txtar.FileDiff{
+		OldName: "file.go",
+		NewName: "file.go",
+		Old:     strings.Repeat("same\n", 10) + "old\n" + strings.Repeat("same\n", 10),
+		New:     strings.Repeat("same\n", 10) + "new\n" + strings.Repeat("same\n", 10),
+	}
+
+	want := `diff --git a/file.go b/file.go
+--- a/file.go
++++ b/file.go
+@@ -8,7 +8,7 @@
+ same
+ same
+ same
+-old
++new
+ same
+ same
+ same
+`
+	test.Diff(t, file.Unified(), want)
+
+	// Hunks is the same without the headers
+	test.Diff(t, file.Hunks(), strings.SplitN(want, "\n", 4)[3])
+
+	unchanged := txtar.FileDiff{OldName: "file.go", NewName: "file.go", Old: "same\n", New: "same\n"}
+	test.Equal(t, unchanged.Hunks(), "")
+}
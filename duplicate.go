@@ -0,0 +1,76 @@
+package txtar
+
+import (
+	"fmt"
+	"iter"
+)
+
+// DuplicatePolicy determines what [ParseWith] does when a document contains more than
+// one file with the same name.
+//
+// The txtar format itself says nothing about duplicate names, but an [Archive] identifies
+// files by name so only one of them can ever be reached through [Archive.Read] or [Archive.Write].
+type DuplicatePolicy int
+
+const (
+	// DuplicateKeepAll keeps every file, as the original [golang.org/x/tools/txtar] package
+	// does. This is the default.
+	//
+	// The first file with a given name is the one used by [Archive.Read] and [Archive.Write],
+	// later ones are still serialised and yielded by [Archive.Files] and may be found with
+	// [Archive.Duplicates].
+	//
+	// [golang.org/x/tools/txtar]: https://pkg.go.dev/golang.org/x/tools/txtar
+	DuplicateKeepAll DuplicatePolicy = iota
+
+	// DuplicateError causes parsing to fail with a [*ParseError] wrapping [ErrDuplicateFile]
+	// that points at the second marker and records the line of the first.
+	DuplicateError
+
+	// DuplicateKeepFirst keeps the first file with a given name and discards any later ones.
+	DuplicateKeepFirst
+
+	// DuplicateKeepLast keeps the contents of the last file with a given name, the file
+	// retains the position of the first so the order of the archive is unchanged. This
+	// matches the behaviour of calling [Archive.Write] for each file in turn.
+	DuplicateKeepLast
+)
+
+// OnDuplicate is a [ParseOption] that sets how files with the same name are handled,
+// see [DuplicatePolicy].
+func OnDuplicate(policy DuplicatePolicy) ParseOption {
+	return func(cfg *parseConfig) error {
+		if policy < DuplicateKeepAll || policy > DuplicateKeepLast {
+			return fmt.Errorf("OnDuplicate: invalid DuplicatePolicy %d", policy)
+		}
+
+		cfg.duplicates = policy
+
+		return nil
+	}
+}
+
+// Duplicates returns an iterator over the files in the archive whose name is shared with
+// an earlier file, yielding their name and contents in archive order.
+//
+// Duplicates come from parsing with [DuplicateKeepAll], the default, or from
+// [FromGoArchive] and [Archive.UnmarshalJSON]. For any other archive the iterator
+// yields nothing.
+func (a *Archive) Duplicates() iter.Seq2[string, string] {
+	if a == nil {
+		return func(yield func(string, string) bool) {}
+	}
+
+	return func(yield func(string, string) bool) {
+		for i, file := range a.files {
+			// The index only points at the first of each name
+			if first, _ := a.find(file.name); first == i {
+				continue
+			}
+
+			if !yield(file.name, file.contents) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package txtar
+
+import (
+	"bytes"
+	"encoding"
+	"errors"
+	"io"
+)
+
+// Compile time interface checks.
+var (
+	_ encoding.TextMarshaler   = (*Archive)(nil)
+	_ encoding.TextUnmarshaler = (*Archive)(nil)
+	_ io.WriterTo              = (*Archive)(nil)
+	_ io.ReaderFrom            = (*Archive)(nil)
+)
+
+// MarshalText implements [encoding.TextMarshaler] for an [Archive], returning its
+// serialised form exactly as [Archive.String] does.
+func (a *Archive) MarshalText() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalText implements [encoding.TextUnmarshaler] for an [Archive], replacing its
+// comment and files with those parsed from text.
+//
+// The text is parsed as by [ParseWith] but according to the archive's own configuration,
+// so a verbatim archive (see [WithVerbatim]) is parsed with [Verbatim], one with the
+// [MarkerQuote] policy with [Quoted] and any [NamePolicy] is applied. It is always parsed
+// with [Lenient] and [DuplicateKeepAll] so that anything [Archive.MarshalText] produces,
+// including an empty or comment only archive or one with duplicate files, survives
+// the round trip.
+//
+// If the text cannot be parsed, the error is returned and the archive left untouched.
+func (a *Archive) UnmarshalText(text []byte) error {
+	if a == nil {
+		return errors.New("UnmarshalText called on a nil Archive")
+	}
+
+	parsed, err := parse(bytes.NewReader(text), "", a.parseOptions())
+	if err != nil {
+		return err
+	}
+
+	parsed.sorted = a.sorted
+	*a = *parsed
+
+	return nil
+}
+
+// WriteTo implements [io.WriterTo] for an [Archive], writing its serialised form
+// (see [Archive.String]) to w and returning the number of bytes written.
+func (a *Archive) WriteTo(w io.Writer) (int64, error) {
+	n, err := io.WriteString(w, a.String())
+	return int64(n), err
+}
+
+// ReadFrom implements [io.ReaderFrom] for an [Archive], reading r until EOF and
+// replacing the archive's comment and files with those parsed from it exactly as
+// [Archive.UnmarshalText] does. It returns the number of bytes read.
+func (a *Archive) ReadFrom(r io.Reader) (int64, error) {
+	if a == nil {
+		return 0, errors.New("ReadFrom called on a nil Archive")
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return int64(len(data)), err
+	}
+
+	return int64(len(data)), a.UnmarshalText(data)
+}
+
+// parseOptions returns the options needed to parse text into an archive configured
+// like this one.
+func (a *Archive) parseOptions() []ParseOption {
+	options := []ParseOption{Lenient(), OnDuplicate(DuplicateKeepAll)}
+
+	if a.verbatim {
+		options = append(options, Verbatim())
+	}
+
+	if a.markers == MarkerQuote {
+		options = append(options, Quoted())
+	}
+
+	if a.names != NameUnchecked {
+		options = append(options, CheckNames(a.names))
+	}
+
+	return options
+}
@@ -0,0 +1,145 @@
+package txtar_test
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"strings"
+	"testing"
+
+	"go.followtheprocess.codes/test"
+	"go.followtheprocess.codes/txtar"
+)
+
+func TestTextRoundTrip(t *testing.T) {
+	archive, err := txtar.New(
+		txtar.WithComment("comment"),
+		txtar.WithFile("a.txt", "a"),
+		txtar.WithFile("b.txt", "b"),
+	)
+	test.Ok(t, err)
+
+	text, err := archive.MarshalText()
+	test.Ok(t, err)
+	test.Diff(t, string(text), archive.String())
+
+	got := &txtar.Archive{}
+	test.Ok(t, got.UnmarshalText(text))
+	test.True(t, txtar.Equal(got, archive))
+
+	// Empty archives survive too
+	empty := &txtar.Archive{}
+	text, err = empty.MarshalText()
+	test.Ok(t, err)
+	test.Equal(t, len(text), 0)
+
+	test.Ok(t, got.UnmarshalText(text))
+	test.Equal(t, got.Size(), 0)
+	test.Equal(t, got.Comment(), "")
+}
+
+func TestTextRoundTripLossless(t *testing.T) {
+	duplicates, err := txtar.ParseWith(
+		strings.NewReader("-- a.txt --\nfirst\n-- b.txt --\nb\n-- a.txt --\nsecond\n"),
+		txtar.OnDuplicate(txtar.DuplicateKeepAll),
+	)
+	test.Ok(t, err)
+
+	commentOnly, err := txtar.New(txtar.WithComment("just a comment"))
+	test.Ok(t, err)
+
+	tests := []struct {
+		archive *txtar.Archive // Archive to round trip
+		name    string         // Name of the test case
+	}{
+		{name: "comment only", archive: commentOnly},
+		{name: "duplicates", archive: duplicates},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, err := tt.archive.MarshalText()
+			test.Ok(t, err)
+
+			got := &txtar.Archive{}
+			test.Ok(t, got.UnmarshalText(text))
+			test.Diff(t, got.String(), tt.archive.String())
+			test.True(t, txtar.Equal(got, tt.archive))
+
+			// ReadFrom too
+			got = &txtar.Archive{}
+			_, err = got.ReadFrom(bytes.NewReader(text))
+			test.Ok(t, err)
+			test.Diff(t, got.String(), tt.archive.String())
+		})
+	}
+}
+
+func TestUnmarshalTextConfig(t *testing.T) {
+	archive, err := txtar.New(txtar.WithVerbatim(), txtar.WithMarkerPolicy(txtar.MarkerQuote))
+	test.Ok(t, err)
+
+	// Parsed verbatim, and the quoted marker undone
+	test.Ok(t, archive.UnmarshalText([]byte("  comment  \n-- a.txt --\n\\-- not a file --\n")))
+	test.Equal(t, archive.Comment(), "  comment  \n")
+
+	contents, ok := archive.Read("a.txt")
+	test.True(t, ok)
+	test.Equal(t, contents, "-- not a file --\n")
+
+	// Duplicates are kept, as MarshalText would write them
+	err = archive.UnmarshalText([]byte("-- a.txt --\na\n-- a.txt --\nduplicate\n"))
+	test.Ok(t, err)
+	test.Equal(t, archive.Size(), 2)
+
+	// A bad document leaves the archive as it was
+	named, err := txtar.New(txtar.WithNamePolicy(txtar.NameClean), txtar.WithFile("a.txt", "a"))
+	test.Ok(t, err)
+
+	before := named.String()
+
+	err = named.UnmarshalText([]byte("-- ../escape.txt --\nnope\n"))
+	test.ErrorIs(t, err, txtar.ErrEscapingName)
+	test.Diff(t, named.String(), before)
+
+	var missing *txtar.Archive
+	test.Err(t, missing.UnmarshalText([]byte("-- a.txt --\n")))
+}
+
+func TestTextVar(t *testing.T) {
+	archive := &txtar.Archive{}
+
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.TextVar(archive, "archive", &txtar.Archive{}, "An archive")
+
+	test.Ok(t, flags.Parse([]string{"-archive", "-- a.txt --\nhello\n"}))
+
+	contents, ok := archive.Read("a.txt")
+	test.True(t, ok)
+	test.Equal(t, contents, "hello\n")
+}
+
+func TestWriteToReadFrom(t *testing.T) {
+	archive, err := txtar.New(txtar.WithComment("comment"), txtar.WithFile("a.txt", "a"))
+	test.Ok(t, err)
+
+	buf := &bytes.Buffer{}
+	w := bufio.NewWriter(buf)
+
+	n, err := archive.WriteTo(w)
+	test.Ok(t, err)
+	test.Ok(t, w.Flush())
+	test.Equal(t, n, int64(len(archive.String())))
+	test.Diff(t, buf.String(), archive.String())
+
+	got := &txtar.Archive{}
+	n, err = got.ReadFrom(bufio.NewReader(buf))
+	test.Ok(t, err)
+	test.Equal(t, n, int64(len(archive.String())))
+	test.True(t, txtar.Equal(got, archive))
+
+	_, err = got.ReadFrom(strings.NewReader("just a comment\n"))
+	test.Ok(t, err)
+	test.Equal(t, got.Comment(), "just a comment")
+	test.Equal(t, got.Size(), 0)
+}
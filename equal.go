@@ -0,0 +1,280 @@
+package txtar
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrNotEqual is the cause of every [MismatchError], so the result of [Mismatch]
+// can be checked with [errors.Is].
+var ErrNotEqual = errors.New("archives are not equal")
+
+// MismatchError describes the first difference found between two archives by [Mismatch].
+type MismatchError struct {
+	Name   string // Name of the file that differs, empty if the difference is not in a single file
+	Reason string // Human readable description of the difference
+}
+
+// Error implements the error interface for a [MismatchError].
+func (e *MismatchError) Error() string {
+	if e.Name == "" {
+		return fmt.Sprintf("%v: %s", ErrNotEqual, e.Reason)
+	}
+
+	return fmt.Sprintf("%v: %s: %s", ErrNotEqual, e.Name, e.Reason)
+}
+
+// Unwrap returns [ErrNotEqual].
+func (e *MismatchError) Unwrap() error {
+	return ErrNotEqual
+}
+
+// EqualOption is a functional option for configuring how archives are compared
+// by [EqualWith] and [Mismatch].
+type EqualOption func(*equalConfig) error
+
+// equalConfig holds the configuration for a single call to [Mismatch].
+type equalConfig struct {
+	contents         func(name, a, b string) bool // Custom file contents comparison, if set
+	ignore           []string                     // Glob patterns for files to leave out of the comparison
+	ignoreOrder      bool                         // Compare files regardless of their order
+	ignoreComment    bool                         // Don't compare the comments
+	ignoreWhitespace bool                         // Normalise whitespace before comparing
+}
+
+// IgnoreOrder is an [EqualOption] that compares archives regardless of the order
+// of their files, so two archives holding the same files in a different order are equal.
+func IgnoreOrder() EqualOption {
+	return func(cfg *equalConfig) error {
+		cfg.ignoreOrder = true
+		return nil
+	}
+}
+
+// IgnoreComment is an [EqualOption] that leaves the archive comments out of the comparison.
+func IgnoreComment() EqualOption {
+	return func(cfg *equalConfig) error {
+		cfg.ignoreComment = true
+		return nil
+	}
+}
+
+// IgnoreWhitespace is an [EqualOption] that ignores differences in line endings and
+// insignificant whitespace when comparing the comments and file contents.
+//
+// Before comparing, "\r\n" is converted to "\n", trailing whitespace is removed from
+// every line and leading and trailing blank lines are removed, so it is mostly useful
+// for archives in verbatim mode (see [WithVerbatim]).
+func IgnoreWhitespace() EqualOption {
+	return func(cfg *equalConfig) error {
+		cfg.ignoreWhitespace = true
+		return nil
+	}
+}
+
+// IgnoreFiles is an [EqualOption] that leaves files matching any of the given glob
+// patterns out of the comparison entirely, matching works exactly as for [WithInclude].
+//
+// Successive calls add to the set of patterns.
+func IgnoreFiles(patterns ...string) EqualOption {
+	return func(cfg *equalConfig) error {
+		if err := validatePatterns(patterns); err != nil {
+			return fmt.Errorf("IgnoreFiles: %w", err)
+		}
+
+		cfg.ignore = append(cfg.ignore, patterns...)
+
+		return nil
+	}
+}
+
+// CompareContents is an [EqualOption] that sets the function used to decide whether
+// the contents of two files with the same name are equal, e.g. to compare JSON files
+// semantically rather than byte for byte.
+//
+// The contents passed to equal have already been normalised if [IgnoreWhitespace]
+// is also given.
+func CompareContents(equal func(name, a, b string) bool) EqualOption {
+	return func(cfg *equalConfig) error {
+		if equal == nil {
+			return errors.New("CompareContents: equal function must not be nil")
+		}
+
+		cfg.contents = equal
+
+		return nil
+	}
+}
+
+// EqualWith is like [Equal] but allows the comparison to be configured with options,
+// for example to ignore the order of files or the comment.
+//
+// If any option is invalid, EqualWith reports false, use [Mismatch] to see the error.
+func EqualWith(a, b *Archive, options ...EqualOption) bool {
+	return Mismatch(a, b, options...) == nil
+}
+
+// Mismatch compares two archives exactly as [EqualWith] does, returning nil if they are
+// equal and otherwise a [*MismatchError] explaining the first difference found.
+//
+// The comments are compared first, then every file in a in order, then any files only
+// in b and finally the order of the files. An error from an invalid option is returned
+// as is, and does not wrap [ErrNotEqual].
+func Mismatch(a, b *Archive, options ...EqualOption) error {
+	cfg := equalConfig{}
+
+	var errs error
+	for _, option := range options {
+		errs = errors.Join(errs, option(&cfg))
+	}
+
+	if errs != nil {
+		return errs
+	}
+
+	if (a == nil) != (b == nil) {
+		first, second := "nil", "not nil"
+		if b == nil {
+			first, second = second, first
+		}
+
+		return &MismatchError{Reason: fmt.Sprintf("first archive is %s, second is %s", first, second)}
+	}
+
+	if !cfg.ignoreComment {
+		if reason := cfg.compare("", a.Comment(), b.Comment()); reason != "" {
+			return &MismatchError{Reason: "comments " + reason}
+		}
+	}
+
+	aNames, aFiles := cfg.group(a)
+	bNames, bFiles := cfg.group(b)
+
+	for _, name := range uniqueNames(aNames) {
+		aContents, bContents := aFiles[name], bFiles[name]
+
+		switch {
+		case len(bContents) == 0:
+			return &MismatchError{Name: name, Reason: "only in first archive"}
+		case len(aContents) != len(bContents):
+			return &MismatchError{
+				Name:   name,
+				Reason: fmt.Sprintf("appears %d times in first archive, %d in second", len(aContents), len(bContents)),
+			}
+		}
+
+		for i := range aContents {
+			if reason := cfg.compare(name, aContents[i], bContents[i]); reason != "" {
+				return &MismatchError{Name: name, Reason: "contents " + reason}
+			}
+		}
+	}
+
+	for _, name := range uniqueNames(bNames) {
+		if len(aFiles[name]) == 0 {
+			return &MismatchError{Name: name, Reason: "only in second archive"}
+		}
+	}
+
+	if !cfg.ignoreOrder {
+		// Both contain exactly the same names by now so the lengths match
+		for i := range aNames {
+			if aNames[i] != bNames[i] {
+				return &MismatchError{
+					Reason: fmt.Sprintf("file %d is %q in first archive, %q in second", i+1, aNames[i], bNames[i]),
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// group returns the names of the files in the archive that are not ignored, in order,
+// along with the contents of each, in order (as there may be duplicates).
+func (c equalConfig) group(archive *Archive) ([]string, map[string][]string) {
+	var names []string
+
+	files := make(map[string][]string)
+
+	if archive == nil {
+		return names, files
+	}
+
+	for _, file := range archive.files {
+		if len(c.ignore) != 0 && matchAny(c.ignore, file.name) {
+			continue
+		}
+
+		names = append(names, file.name)
+		files[file.name] = append(files[file.name], file.contents)
+	}
+
+	return names, files
+}
+
+// compare compares a and b (the contents of the named file, or the comment if name is empty)
+// according to the configuration, returning a description of how they differ, or "" if
+// they are considered equal.
+func (c equalConfig) compare(name, a, b string) string {
+	if c.ignoreWhitespace {
+		a, b = trimWhitespace(a), trimWhitespace(b)
+	}
+
+	if name != "" && c.contents != nil {
+		if c.contents(name, a, b) {
+			return ""
+		}
+
+		return "differ"
+	}
+
+	if a == b {
+		return ""
+	}
+
+	aLines, bLines := strings.SplitAfter(a, "\n"), strings.SplitAfter(b, "\n")
+	for i := range max(len(aLines), len(bLines)) {
+		var aLine, bLine string
+		if i < len(aLines) {
+			aLine = aLines[i]
+		}
+
+		if i < len(bLines) {
+			bLine = bLines[i]
+		}
+
+		if aLine != bLine {
+			return fmt.Sprintf("differ at line %d: %q != %q", i+1, aLine, bLine)
+		}
+	}
+
+	// Unreachable as a != b, but be safe
+	return "differ"
+}
+
+// trimWhitespace normalises s for an [IgnoreWhitespace] comparison.
+func trimWhitespace(s string) string {
+	lines := strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t\r")
+	}
+
+	return strings.Trim(strings.Join(lines, "\n"), "\n")
+}
+
+// uniqueNames returns names with any repeats after the first removed.
+func uniqueNames(names []string) []string {
+	seen := make(map[string]bool, len(names))
+	unique := make([]string, 0, len(names))
+
+	for _, name := range names {
+		if !seen[name] {
+			seen[name] = true
+			unique = append(unique, name)
+		}
+	}
+
+	return unique
+}
@@ -0,0 +1,192 @@
+package txtar_test
+
+import (
+	"encoding/json"
+	"errors"
+	"path"
+	"reflect"
+	"testing"
+
+	"go.followtheprocess.codes/test"
+	"go.followtheprocess.codes/txtar"
+)
+
+func TestEqualWith(t *testing.T) {
+	tests := []struct {
+		a       *txtar.Archive      // First archive
+		b       *txtar.Archive      // Second archive
+		name    string              // Name of the test case
+		file    string              // Expected Name in the MismatchError
+		reason  string              // Expected Reason in the MismatchError, "" means equal
+		options []txtar.EqualOption // Options to compare with
+	}{
+		{
+			name: "identical",
+			a:    equalArchive(t, "comment", "a.txt", "a", "b.txt", "b"),
+			b:    equalArchive(t, "comment", "a.txt", "a", "b.txt", "b"),
+		},
+		{
+			name: "both nil",
+		},
+		{
+			name:   "one nil",
+			a:      equalArchive(t, "comment"),
+			reason: "first archive is not nil, second is nil",
+		},
+		{
+			name:   "comment",
+			a:      equalArchive(t, "one\ntwo", "a.txt", "a"),
+			b:      equalArchive(t, "one\n2", "a.txt", "a"),
+			reason: `comments differ at line 2: "two" != "2"`,
+		},
+		{
+			name:    "ignore comment",
+			a:       equalArchive(t, "one", "a.txt", "a"),
+			b:       equalArchive(t, "two", "a.txt", "a"),
+			options: []txtar.EqualOption{txtar.IgnoreComment()},
+		},
+		{
+			name:   "contents",
+			a:      equalArchive(t, "", "a.txt", "same\nold"),
+			b:      equalArchive(t, "", "a.txt", "same\nnew"),
+			file:   "a.txt",
+			reason: `contents differ at line 2: "old\n" != "new\n"`,
+		},
+		{
+			name:   "missing",
+			a:      equalArchive(t, "", "a.txt", "a", "b.txt", "b"),
+			b:      equalArchive(t, "", "a.txt", "a"),
+			file:   "b.txt",
+			reason: "only in first archive",
+		},
+		{
+			name:   "extra",
+			a:      equalArchive(t, "", "a.txt", "a"),
+			b:      equalArchive(t, "", "a.txt", "a", "b.txt", "b"),
+			file:   "b.txt",
+			reason: "only in second archive",
+		},
+		{
+			name:   "order",
+			a:      equalArchive(t, "", "a.txt", "a", "b.txt", "b"),
+			b:      equalArchive(t, "", "b.txt", "b", "a.txt", "a"),
+			reason: `file 1 is "a.txt" in first archive, "b.txt" in second`,
+		},
+		{
+			name:    "ignore order",
+			a:       equalArchive(t, "", "a.txt", "a", "b.txt", "b"),
+			b:       equalArchive(t, "", "b.txt", "b", "a.txt", "a"),
+			options: []txtar.EqualOption{txtar.IgnoreOrder()},
+		},
+		{
+			name:    "ignore files",
+			a:       equalArchive(t, "", "a.txt", "a", "gen/out.txt", "one", "x.log", "one"),
+			b:       equalArchive(t, "", "gen/out.txt", "two", "a.txt", "a", "y.log", "two"),
+			options: []txtar.EqualOption{txtar.IgnoreFiles("gen/*", "*.log")},
+		},
+		{
+			name:    "ignore whitespace",
+			a:       verbatimArchive(t, "comment  \r\n", "a.txt", "\n\none  \r\ntwo\n\n\n"),
+			b:       verbatimArchive(t, "comment\n", "a.txt", "one\ntwo\n"),
+			options: []txtar.EqualOption{txtar.IgnoreWhitespace()},
+		},
+		{
+			name:   "whitespace matters by default",
+			a:      verbatimArchive(t, "comment\n", "a.txt", "one  \ntwo\n"),
+			b:      verbatimArchive(t, "comment\n", "a.txt", "one\ntwo\n"),
+			file:   "a.txt",
+			reason: `contents differ at line 1: "one  \n" != "one\n"`,
+		},
+		{
+			name:    "compare contents",
+			a:       equalArchive(t, "", "data.json", `{"a": 1, "b": [1, 2]}`, "other.txt", "same"),
+			b:       equalArchive(t, "", "data.json", `{"b":[1,2],"a":1}`, "other.txt", "same"),
+			options: []txtar.EqualOption{txtar.CompareContents(jsonEqual)},
+		},
+		{
+			name:    "compare contents unequal",
+			a:       equalArchive(t, "", "data.json", `{"a": 1}`),
+			b:       equalArchive(t, "", "data.json", `{"a": 2}`),
+			options: []txtar.EqualOption{txtar.CompareContents(jsonEqual)},
+			file:    "data.json",
+			reason:  "contents differ",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := txtar.Mismatch(tt.a, tt.b, tt.options...)
+			test.Equal(t, txtar.EqualWith(tt.a, tt.b, tt.options...), err == nil)
+
+			if tt.reason == "" {
+				test.Ok(t, err)
+				return
+			}
+
+			test.ErrorIs(t, err, txtar.ErrNotEqual)
+
+			mismatch := test.ErrorAs[*txtar.MismatchError](t, err)
+			test.Equal(t, mismatch.Name, tt.file)
+			test.Equal(t, mismatch.Reason, tt.reason)
+		})
+	}
+}
+
+func TestEqualWithBadOptions(t *testing.T) {
+	archive := equalArchive(t, "comment")
+
+	err := txtar.Mismatch(archive, archive, txtar.IgnoreFiles("[bad"), txtar.CompareContents(nil))
+	test.Err(t, err)
+	test.False(t, errors.Is(err, txtar.ErrNotEqual))
+	test.False(t, txtar.EqualWith(archive, archive, txtar.IgnoreFiles("[bad")))
+}
+
+// equalArchive builds an archive with the given comment and alternating file
+// names and contents.
+func equalArchive(t *testing.T, comment string, files ...string) *txtar.Archive {
+	t.Helper()
+	return buildArchive(t, append([]txtar.Option{txtar.WithComment(comment)}, fileOptions(files)...)...)
+}
+
+// verbatimArchive is like equalArchive but the archive is in verbatim mode.
+func verbatimArchive(t *testing.T, comment string, files ...string) *txtar.Archive {
+	t.Helper()
+
+	options := []txtar.Option{txtar.WithVerbatim(), txtar.WithComment(comment)}
+
+	return buildArchive(t, append(options, fileOptions(files)...)...)
+}
+
+// buildArchive returns a new archive built from options.
+func buildArchive(t *testing.T, options ...txtar.Option) *txtar.Archive {
+	t.Helper()
+
+	archive, err := txtar.New(options...)
+	test.Ok(t, err)
+
+	return archive
+}
+
+// fileOptions turns alternating file names and contents into WithFile options.
+func fileOptions(files []string) []txtar.Option {
+	options := make([]txtar.Option, 0, len(files)/2)
+	for i := 0; i+1 < len(files); i += 2 {
+		options = append(options, txtar.WithFile(files[i], files[i+1]))
+	}
+
+	return options
+}
+
+// jsonEqual compares JSON files semantically and everything else exactly.
+func jsonEqual(name, a, b string) bool {
+	if path.Ext(name) != ".json" {
+		return a == b
+	}
+
+	var aValue, bValue any
+	if json.Unmarshal([]byte(a), &aValue) != nil || json.Unmarshal([]byte(b), &bValue) != nil {
+		return false
+	}
+
+	return reflect.DeepEqual(aValue, bValue)
+}
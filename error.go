@@ -0,0 +1,111 @@
+package txtar
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Sentinel errors describing why an archive could not be parsed, these are
+// available as the cause of a [ParseError] and so can be checked with [errors.Is].
+var (
+	// ErrEmpty is the cause of a [ParseError] when the archive contains no data at all.
+	ErrEmpty = errors.New("cannot parse empty txtar archive")
+
+	// ErrNoFiles is the cause of a [ParseError] when the archive contains no file markers.
+	ErrNoFiles = errors.New("archive contains no files")
+
+	// ErrUnterminatedMarker is the cause of a [ParseError] when the archive contains the
+	// start of a file marker ("-- ") but no complete "-- NAME --" marker line.
+	ErrUnterminatedMarker = errors.New("unterminated file marker")
+
+	// ErrDuplicateFile is the cause of a [ParseError] when the archive contains more than
+	// one file with the same name and the [DuplicatePolicy] is [DuplicateError].
+	ErrDuplicateFile = errors.New("duplicate file name")
+)
+
+// ParseError is the error returned from [Parse] and [ParseFile] when the archive
+// is malformed.
+//
+// It describes where in the source the problem was found and wraps one of the
+// package's sentinel errors (e.g. [ErrNoFiles]) as its cause, so callers may use
+// either [errors.As] to get at the position or [errors.Is] to check the cause.
+type ParseError struct {
+	Err    error  // The underlying cause, one of the package sentinel errors or a [*NameError]
+	Name   string // Name of the source being parsed e.g. a file path, may be empty
+	Marker string // The offending marker text, if the error relates to one
+	Line   int    // 1-based line number, 0 if the error does not relate to a specific position
+	Col    int    // 1-based column (in bytes), 0 if the error does not relate to a specific position
+
+	// FirstLine is the 1-based line number of the first file marker with the same name
+	// when the cause is [ErrDuplicateFile], 0 otherwise.
+	FirstLine int
+}
+
+// Error implements the error interface for a [ParseError].
+func (e *ParseError) Error() string {
+	s := &strings.Builder{}
+	s.WriteString("Parse: ")
+
+	// Position is formatted like the go toolchain: name:line:col
+	s.WriteString(e.Name)
+
+	if e.Line > 0 {
+		if e.Name != "" {
+			s.WriteByte(':')
+		}
+
+		s.WriteString(strconv.Itoa(e.Line))
+		s.WriteByte(':')
+		s.WriteString(strconv.Itoa(e.Col))
+	}
+
+	if e.Name != "" || e.Line > 0 {
+		s.WriteString(": ")
+	}
+
+	if e.Err != nil {
+		s.WriteString(e.Err.Error())
+	} else {
+		s.WriteString("malformed txtar archive")
+	}
+
+	if e.Marker != "" {
+		s.WriteByte(' ')
+		s.WriteString(strconv.Quote(e.Marker))
+	}
+
+	if e.FirstLine > 0 {
+		s.WriteString(" (first defined on line ")
+		s.WriteString(strconv.Itoa(e.FirstLine))
+		s.WriteByte(')')
+	}
+
+	return s.String()
+}
+
+// Unwrap returns the underlying cause of the [ParseError].
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// position returns the 1-based line and column of the byte at offset in data.
+func position(data []byte, offset int) (line, col int) {
+	before := data[:offset]
+	line = bytes.Count(before, []byte("\n")) + 1
+	col = offset - bytes.LastIndexByte(before, '\n')
+
+	return line, col
+}
+
+// lineAt returns the remainder of the line in data starting at offset, not
+// including the trailing newline.
+func lineAt(data []byte, offset int) string {
+	rest := data[offset:]
+	if i := bytes.IndexByte(rest, '\n'); i >= 0 {
+		rest = rest[:i]
+	}
+
+	return string(rest)
+}
@@ -0,0 +1,157 @@
+package txtar
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+const (
+	defaultFilePerm = 0o644 // Default permissions for extracted files
+	defaultDirPerm  = 0o755 // Permissions for directories created during extraction
+)
+
+// OverwritePolicy determines what [Archive.Extract] does when a file it is
+// extracting already exists on disk.
+type OverwritePolicy int
+
+const (
+	// OverwriteNever causes Extract to report an error for any file that already exists,
+	// leaving it untouched. This is the default.
+	OverwriteNever OverwritePolicy = iota
+
+	// OverwriteSkip causes Extract to silently leave existing files untouched.
+	OverwriteSkip
+
+	// OverwriteAlways causes Extract to replace the contents of existing files.
+	OverwriteAlways
+)
+
+// ExtractOption is a functional option for configuring [Archive.Extract].
+type ExtractOption func(*extractConfig) error
+
+// extractConfig holds the configuration for a single call to [Archive.Extract].
+type extractConfig struct {
+	overwrite OverwritePolicy // What to do about existing files
+	perm      fs.FileMode     // Permissions for created files
+}
+
+// WithOverwrite is an [ExtractOption] that sets what to do when a file being
+// extracted already exists, see [OverwritePolicy].
+func WithOverwrite(policy OverwritePolicy) ExtractOption {
+	return func(cfg *extractConfig) error {
+		if policy < OverwriteNever || policy > OverwriteAlways {
+			return fmt.Errorf("WithOverwrite: invalid OverwritePolicy %d", policy)
+		}
+
+		cfg.overwrite = policy
+
+		return nil
+	}
+}
+
+// WithPerm is an [ExtractOption] that sets the permission bits of extracted
+// files, the default is 0o644.
+//
+// Directories are always created with permissions 0o755. In both cases the
+// process umask applies as usual.
+func WithPerm(perm fs.FileMode) ExtractOption {
+	return func(cfg *extractConfig) error {
+		if perm&^fs.ModePerm != 0 {
+			return fmt.Errorf("WithPerm: %s is not a valid set of permission bits", perm)
+		}
+
+		cfg.perm = perm
+
+		return nil
+	}
+}
+
+// Extract writes every file in the archive to disk under the directory dir, creating
+// dir itself and any parent directories as needed. Slash separated file names
+// like "a/b/c.go" are written to the corresponding nested path.
+//
+// Names are cleaned as by [ValidName] so files with empty or absolute names or names
+// that would escape dir (e.g. "../x") are rejected with a [*NameError], and all access
+// happens through an [os.Root] so symlinks cannot be used to escape dir either. Names
+// only [ValidName] rejects for not surviving a file marker (e.g. "a -- b") are fine.
+//
+// Extract does not stop at the first failure, it attempts to extract every file and
+// reports all the errors together.
+func (a *Archive) Extract(dir string, options ...ExtractOption) error {
+	if a == nil {
+		return errors.New("Extract called on a nil Archive")
+	}
+
+	cfg := extractConfig{perm: defaultFilePerm}
+
+	var errs error
+	for _, option := range options {
+		errs = errors.Join(errs, option(&cfg))
+	}
+
+	if errs != nil {
+		return errs
+	}
+
+	if err := os.MkdirAll(dir, defaultDirPerm); err != nil {
+		return err
+	}
+
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+
+	for _, file := range a.files {
+		errs = errors.Join(errs, extractFile(root, file, cfg))
+	}
+
+	return errs
+}
+
+// extractFile writes a single file to root.
+func extractFile(root *os.Root, file file, cfg extractConfig) error {
+	// Only the path matters on disk, a name that couldn't be written as a file marker
+	// (see ValidName) is perfectly fine as a file name
+	clean, err := localName(file.name)
+	if err != nil {
+		return fmt.Errorf("Extract: %w", &NameError{Name: file.name, Err: err})
+	}
+
+	// localName has already ruled out anything escaping in a portable way, IsLocal
+	// additionally catches platform specifics like reserved names on Windows
+	name := filepath.FromSlash(clean)
+	if !filepath.IsLocal(name) {
+		return fmt.Errorf("Extract: %q: file name escapes the destination directory", file.name)
+	}
+
+	if dir := filepath.Dir(name); dir != "." {
+		if err := root.MkdirAll(dir, defaultDirPerm); err != nil {
+			return fmt.Errorf("Extract: %q: %w", file.name, err)
+		}
+	}
+
+	flags := os.O_WRONLY | os.O_CREATE
+	if cfg.overwrite == OverwriteAlways {
+		flags |= os.O_TRUNC
+	} else {
+		flags |= os.O_EXCL
+	}
+
+	f, err := root.OpenFile(name, flags, cfg.perm)
+	if err != nil {
+		if cfg.overwrite == OverwriteSkip && errors.Is(err, fs.ErrExist) {
+			return nil
+		}
+
+		return fmt.Errorf("Extract: %q: %w", file.name, err)
+	}
+
+	_, err = f.WriteString(file.contents)
+
+	return errors.Join(err, f.Close())
+}
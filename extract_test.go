@@ -0,0 +1,176 @@
+package txtar_test
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"go.followtheprocess.codes/test"
+	"go.followtheprocess.codes/txtar"
+)
+
+func TestExtract(t *testing.T) {
+	archive, err := txtar.New(
+		txtar.WithComment("Comments aren't extracted"),
+		txtar.WithFile("top.txt", "top level"),
+		txtar.WithFile("a/b/c.go", "package b"),
+		txtar.WithFile("a/empty.txt", ""),
+		txtar.WithFile("a -- b.txt", "dashes"),
+	)
+	test.Ok(t, err)
+
+	// Should create the destination directory if it's not there
+	dir := filepath.Join(t.TempDir(), "dest")
+	test.Ok(t, archive.Extract(dir))
+
+	for name, want := range map[string]string{
+		"top.txt":     "top level\n",
+		"a/b/c.go":    "package b\n",
+		"a/empty.txt": "",
+		"a -- b.txt":  "dashes\n",
+	} {
+		got, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		test.Ok(t, err, test.Context("Could not read extracted file %s", name))
+		test.Equal(t, string(got), want)
+	}
+}
+
+func TestExtractUnsafe(t *testing.T) {
+	archive, err := txtar.New(
+		txtar.WithFile("fine.txt", "fine"),
+		txtar.WithFile("/etc/absolute", "absolute"),
+		txtar.WithFile("../escape.txt", "escape"),
+		txtar.WithFile("a/../../sneaky.txt", "sneaky"),
+		txtar.WithFile("also/fine.txt", "fine"),
+	)
+	test.Ok(t, err)
+
+	parent := t.TempDir()
+	dir := filepath.Join(parent, "dest")
+
+	err = archive.Extract(dir)
+	test.ErrorIs(t, err, txtar.ErrAbsoluteName)
+	test.ErrorIs(t, err, txtar.ErrEscapingName)
+
+	// The good ones should still have been written
+	for _, name := range []string{"fine.txt", "also/fine.txt"} {
+		_, err := os.Stat(filepath.Join(dir, filepath.FromSlash(name)))
+		test.Ok(t, err, test.Context("%s should have been extracted", name))
+	}
+
+	// But nothing outside of dir
+	for _, name := range []string{"escape.txt", "sneaky.txt"} {
+		_, err := os.Stat(filepath.Join(parent, name))
+		test.ErrorIs(t, err, fs.ErrNotExist)
+	}
+}
+
+func TestExtractSymlinkEscape(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Symlinks need special privileges on windows")
+	}
+
+	parent := t.TempDir()
+	outside := filepath.Join(parent, "outside")
+	dir := filepath.Join(parent, "dest")
+
+	test.Ok(t, os.Mkdir(outside, 0o755))
+	test.Ok(t, os.Mkdir(dir, 0o755))
+	test.Ok(t, os.Symlink(outside, filepath.Join(dir, "link")))
+
+	archive, err := txtar.New(txtar.WithFile("link/file.txt", "should not escape"))
+	test.Ok(t, err)
+
+	test.Err(t, archive.Extract(dir))
+
+	_, err = os.Stat(filepath.Join(outside, "file.txt"))
+	test.ErrorIs(t, err, fs.ErrNotExist)
+}
+
+func TestExtractOverwrite(t *testing.T) {
+	tests := []struct {
+		name    string                // Name of the test case
+		want    string                // Expected contents of the existing file afterwards
+		options []txtar.ExtractOption // Options to pass to Extract
+		wantErr bool                  // Whether Extract should return an error
+	}{
+		{
+			name:    "default",
+			options: nil,
+			want:    "original",
+			wantErr: true,
+		},
+		{
+			name:    "never",
+			options: []txtar.ExtractOption{txtar.WithOverwrite(txtar.OverwriteNever)},
+			want:    "original",
+			wantErr: true,
+		},
+		{
+			name:    "skip",
+			options: []txtar.ExtractOption{txtar.WithOverwrite(txtar.OverwriteSkip)},
+			want:    "original",
+			wantErr: false,
+		},
+		{
+			name:    "always",
+			options: []txtar.ExtractOption{txtar.WithOverwrite(txtar.OverwriteAlways)},
+			want:    "replaced\n",
+			wantErr: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			existing := filepath.Join(dir, "existing.txt")
+			test.Ok(t, os.WriteFile(existing, []byte("original"), 0o644))
+
+			archive, err := txtar.New(
+				txtar.WithFile("existing.txt", "replaced"),
+				txtar.WithFile("new.txt", "new"),
+			)
+			test.Ok(t, err)
+
+			err = archive.Extract(dir, tt.options...)
+			test.WantErr(t, err, tt.wantErr)
+
+			got, err := os.ReadFile(existing)
+			test.Ok(t, err)
+			test.Equal(t, string(got), tt.want)
+
+			// The new file should be written regardless
+			_, err = os.Stat(filepath.Join(dir, "new.txt"))
+			test.Ok(t, err)
+		})
+	}
+}
+
+func TestExtractPerm(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Unix permissions don't apply on windows")
+	}
+
+	archive, err := txtar.New(txtar.WithFile("script.sh", "echo hello"))
+	test.Ok(t, err)
+
+	dir := t.TempDir()
+	test.Ok(t, archive.Extract(dir, txtar.WithPerm(0o700)))
+
+	info, err := os.Stat(filepath.Join(dir, "script.sh"))
+	test.Ok(t, err)
+	test.Equal(t, info.Mode().Perm(), fs.FileMode(0o700))
+}
+
+func TestExtractBadOptions(t *testing.T) {
+	archive, err := txtar.New()
+	test.Ok(t, err)
+
+	err = archive.Extract(t.TempDir(), txtar.WithPerm(fs.ModeDir|0o644), txtar.WithOverwrite(42))
+	test.Err(t, err)
+
+	var nilArchive *txtar.Archive
+	test.Err(t, nilArchive.Extract(t.TempDir()))
+}
@@ -0,0 +1,33 @@
+package txtar
+
+import (
+	"bytes"
+)
+
+// Format returns the canonical formatting of the txtar document src, applying any
+// number of [DumpOption] (e.g. [Sorted] to sort the files by name).
+//
+// The canonical form is what [Archive.String] emits for the parsed archive: "\r\n"
+// line endings become "\n", whitespace around the names in file markers and around
+// the comment and file contents is trimmed, every file ends in a single newline and
+// a single blank line separates the comment from the first file.
+//
+// src is parsed with [Lenient] and [DuplicateKeepAll] so anything the original package
+// can read is formatted, including a document that is only a comment and one with
+// duplicate files. Formatting is idempotent, formatting already formatted src returns
+// it unchanged.
+func Format(src []byte, options ...DumpOption) ([]byte, error) {
+	archive, err := ParseWith(bytes.NewReader(src), Lenient(), OnDuplicate(DuplicateKeepAll))
+	if err != nil {
+		return nil, err
+	}
+
+	buf := &bytes.Buffer{}
+	buf.Grow(len(src))
+
+	if err := Dump(buf, archive, options...); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,76 @@
+package txtar_test
+
+import (
+	"bytes"
+	"testing"
+
+	"go.followtheprocess.codes/test"
+	"go.followtheprocess.codes/txtar"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		name    string             // Name of the test case
+		src     string             // Document to format
+		want    string             // Expected canonical form
+		options []txtar.DumpOption // Options to format with
+	}{
+		{
+			name: "canonical",
+			src:  "comment\n\n-- a.txt --\na\n-- b.txt --\nb\n",
+			want: "comment\n\n-- a.txt --\na\n-- b.txt --\nb\n",
+		},
+		{
+			name: "messy",
+			src:  "\r\n  comment  \r\n\r\n\r\n--  b.txt  --\r\n\r\nb   \r\n\r\n-- a.txt --\r\na",
+			want: "comment\n\n-- b.txt --\nb\n-- a.txt --\na\n",
+		},
+		{
+			name: "no blank line after comment",
+			src:  "comment\n-- a.txt --\na\n",
+			want: "comment\n\n-- a.txt --\na\n",
+		},
+		{
+			name: "comment only",
+			src:  "  just comment  \r\n\r\n",
+			want: "just comment\n",
+		},
+		{
+			name: "empty",
+			src:  "",
+			want: "",
+		},
+		{
+			name: "duplicates",
+			src:  "-- a.txt --\nfirst\n-- b.txt --\nb  \n-- a.txt --\nsecond",
+			want: "-- a.txt --\nfirst\n-- b.txt --\nb\n-- a.txt --\nsecond\n",
+		},
+		{
+			name:    "sorted",
+			src:     "-- b.txt --\nb\n-- a.txt --\na\n",
+			options: []txtar.DumpOption{txtar.Sorted()},
+			want:    "-- a.txt --\na\n-- b.txt --\nb\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := txtar.Format([]byte(tt.src), tt.options...)
+			test.Ok(t, err)
+			test.Diff(t, string(got), tt.want)
+
+			// Idempotent
+			again, err := txtar.Format(got, tt.options...)
+			test.Ok(t, err)
+			test.True(t, bytes.Equal(again, got), test.Context("Format is not idempotent"))
+
+			// And the same as String
+			archive, err := txtar.ParseWith(bytes.NewReader(got), txtar.Lenient())
+			test.Ok(t, err)
+
+			if len(tt.options) == 0 {
+				test.Diff(t, archive.String(), string(got))
+			}
+		})
+	}
+}
@@ -0,0 +1,341 @@
+package txtar
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"unicode/utf8"
+)
+
+var (
+	// ErrBinaryFile is returned when building an archive from a file that appears to
+	// contain binary data, which the txtar format cannot represent.
+	ErrBinaryFile = errors.New("file contains binary data")
+
+	// ErrFileTooLarge is returned when building an archive from a file that exceeds
+	// the size limit set with [WithMaxSize].
+	ErrFileTooLarge = errors.New("file exceeds maximum size")
+)
+
+// BinaryPolicy determines what happens when a binary file is encountered
+// while building an [Archive] from a directory tree, tar or zip file.
+//
+// A file is considered binary if it contains a NUL byte or is not valid UTF-8.
+type BinaryPolicy int
+
+const (
+	// BinaryError causes an error wrapping [ErrBinaryFile] to be reported
+	// for each binary file. This is the default.
+	BinaryError BinaryPolicy = iota
+
+	// BinarySkip causes binary files to be silently left out of the archive.
+	BinarySkip
+)
+
+// FromOption is a functional option for configuring [FromFS], [FromDir], [FromTar]
+// and [FromZip].
+type FromOption func(*fromConfig) error
+
+// fromConfig holds the configuration for a single call to [FromFS].
+type fromConfig struct {
+	include []string     // Glob patterns a file must match (any of) to be included, empty means everything
+	exclude []string     // Glob patterns for files and directories to leave out
+	maxSize int64        // Maximum file size in bytes, 0 means no limit
+	binary  BinaryPolicy // What to do with binary files
+	markers MarkerPolicy // Marker policy of the resulting archive
+}
+
+// WithInclude is a [FromOption] that restricts the archive to files matching at
+// least one of the given glob patterns (see [path.Match]).
+//
+// A pattern matches a file if it matches either its full slash separated path
+// (e.g. "cmd/*/main.go") or just its base name (e.g. "*.go"). Successive calls add
+// to the set of patterns.
+func WithInclude(patterns ...string) FromOption {
+	return func(cfg *fromConfig) error {
+		if err := validatePatterns(patterns); err != nil {
+			return fmt.Errorf("WithInclude: %w", err)
+		}
+
+		cfg.include = append(cfg.include, patterns...)
+
+		return nil
+	}
+}
+
+// WithExclude is a [FromOption] that leaves out files and directories matching
+// any of the given glob patterns (see [path.Match]), matching works exactly as
+// for [WithInclude].
+//
+// Excluding a directory excludes everything beneath it and exclusion takes
+// precedence over inclusion. Successive calls add to the set of patterns.
+func WithExclude(patterns ...string) FromOption {
+	return func(cfg *fromConfig) error {
+		if err := validatePatterns(patterns); err != nil {
+			return fmt.Errorf("WithExclude: %w", err)
+		}
+
+		cfg.exclude = append(cfg.exclude, patterns...)
+
+		return nil
+	}
+}
+
+// WithBinary is a [FromOption] that sets what to do when a binary file is
+// encountered, see [BinaryPolicy].
+func WithBinary(policy BinaryPolicy) FromOption {
+	return func(cfg *fromConfig) error {
+		if policy < BinaryError || policy > BinarySkip {
+			return fmt.Errorf("WithBinary: invalid BinaryPolicy %d", policy)
+		}
+
+		cfg.binary = policy
+
+		return nil
+	}
+}
+
+// WithMarkers is a [FromOption] that sets the [MarkerPolicy] of the resulting archive,
+// exactly as [WithMarkerPolicy] does for [New].
+//
+// The default, [MarkerReject], makes any file containing a line that looks like a file
+// marker an error, so use [MarkerQuote] when building an archive from a tree that
+// itself contains txtar files (e.g. testdata).
+func WithMarkers(policy MarkerPolicy) FromOption {
+	return func(cfg *fromConfig) error {
+		if policy < MarkerReject || policy > MarkerQuote {
+			return fmt.Errorf("WithMarkers: invalid MarkerPolicy %d", policy)
+		}
+
+		cfg.markers = policy
+
+		return nil
+	}
+}
+
+// WithMaxSize is a [FromOption] that sets the maximum size in bytes of any one
+// file, files larger than this cause an error wrapping [ErrFileTooLarge].
+func WithMaxSize(size int64) FromOption {
+	return func(cfg *fromConfig) error {
+		if size <= 0 {
+			return fmt.Errorf("WithMaxSize: size must be positive, got %d", size)
+		}
+
+		cfg.maxSize = size
+
+		return nil
+	}
+}
+
+// FromFS builds an [Archive] from every regular file in fsys, applying any number
+// of options to control which files are included.
+//
+// File names in the archive are the slash separated paths within fsys and files
+// are added in sorted order by name so the result is deterministic. Contents are
+// trimmed exactly as [Archive.Write] does.
+//
+// Like [New], errors are not reported one at a time, FromFS attempts every
+// file and reports all the errors together.
+func FromFS(fsys fs.FS, options ...FromOption) (*Archive, error) {
+	cfg := fromConfig{}
+
+	var errs error
+	for _, option := range options {
+		errs = errors.Join(errs, option(&cfg))
+	}
+
+	if errs != nil {
+		return nil, errs
+	}
+
+	var files []file
+
+	err := fs.WalkDir(fsys, ".", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			errs = errors.Join(errs, err)
+			return nil
+		}
+
+		if name == "." {
+			return nil
+		}
+
+		if cfg.excluded(name) {
+			if entry.IsDir() {
+				return fs.SkipDir
+			}
+
+			return nil
+		}
+
+		if !entry.Type().IsRegular() || !cfg.included(name) {
+			return nil
+		}
+
+		contents, ok, err := readFile(fsys, name, entry, cfg)
+		if err != nil {
+			errs = errors.Join(errs, fmt.Errorf("FromFS: %s: %w", name, err))
+			return nil
+		}
+
+		if ok {
+			files = append(files, file{name: name, contents: contents})
+		}
+
+		return nil
+	})
+
+	errs = errors.Join(errs, err)
+	if errs != nil {
+		return nil, errs
+	}
+
+	slices.SortFunc(files, func(a, b file) int { return cmp.Compare(a.name, b.name) })
+
+	archive := &Archive{markers: cfg.markers}
+	for _, file := range files {
+		errs = errors.Join(errs, archive.Write(file.name, file.contents))
+	}
+
+	if errs != nil {
+		return nil, errs
+	}
+
+	return archive, nil
+}
+
+// FromDir is a convenience wrapper around [FromFS] when building an archive
+// from a directory on disk.
+func FromDir(dir string, options ...FromOption) (*Archive, error) {
+	return FromFS(os.DirFS(dir), options...)
+}
+
+// readFile reads a single file for [FromFS], applying the size limit and binary policy.
+//
+// It returns the file contents and whether the file should be added to the archive.
+func readFile(fsys fs.FS, name string, entry fs.DirEntry, cfg fromConfig) (string, bool, error) {
+	if cfg.maxSize > 0 {
+		info, err := entry.Info()
+		if err != nil {
+			return "", false, err
+		}
+
+		if info.Size() > cfg.maxSize {
+			return "", false, fmt.Errorf("%w (%d > %d bytes)", ErrFileTooLarge, info.Size(), cfg.maxSize)
+		}
+	}
+
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return "", false, err
+	}
+
+	return cfg.contents(data)
+}
+
+// readMember reads a single member of a tar or zip file for [FromTar] and [FromZip],
+// validating its name and applying the configured patterns, size limit and binary policy.
+//
+// It returns the name to store the file under, its contents and whether it should be
+// added to the archive.
+func readMember(name string, size int64, open func() (io.Reader, error), cfg fromConfig) (string, string, bool, error) {
+	name, err := ValidName(name)
+	if err != nil {
+		return "", "", false, err
+	}
+
+	// Unlike a walk, members can't be skipped a directory at a time so check every parent
+	for dir := name; dir != "."; dir = path.Dir(dir) {
+		if cfg.excluded(dir) {
+			return "", "", false, nil
+		}
+	}
+
+	if !cfg.included(name) {
+		return "", "", false, nil
+	}
+
+	if cfg.maxSize > 0 && size > cfg.maxSize {
+		return "", "", false, fmt.Errorf("%w (%d > %d bytes)", ErrFileTooLarge, size, cfg.maxSize)
+	}
+
+	r, err := open()
+	if err != nil {
+		return "", "", false, err
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", "", false, err
+	}
+
+	contents, ok, err := cfg.contents(data)
+
+	return name, contents, ok, err
+}
+
+// contents applies the binary policy to the data read from a file, returning the
+// contents and whether the file should be added to the archive.
+func (c fromConfig) contents(data []byte) (string, bool, error) {
+	if isBinary(data) {
+		if c.binary == BinarySkip {
+			return "", false, nil
+		}
+
+		return "", false, ErrBinaryFile
+	}
+
+	return string(data), true, nil
+}
+
+// included reports whether name should be included given the configured
+// include patterns.
+func (c fromConfig) included(name string) bool {
+	return len(c.include) == 0 || matchAny(c.include, name)
+}
+
+// excluded reports whether name should be excluded given the configured
+// exclude patterns.
+func (c fromConfig) excluded(name string) bool {
+	return matchAny(c.exclude, name)
+}
+
+// matchAny reports whether any of the glob patterns match name, either in
+// full or by its base name.
+func matchAny(patterns []string, name string) bool {
+	base := path.Base(name)
+	for _, pattern := range patterns {
+		// Patterns have already been validated so errors are impossible
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+
+		if ok, _ := path.Match(pattern, base); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// validatePatterns checks every pattern is a syntactically valid glob.
+func validatePatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("bad pattern %q: %w", pattern, err)
+		}
+	}
+
+	return nil
+}
+
+// isBinary reports whether data looks like the contents of a binary file.
+func isBinary(data []byte) bool {
+	return bytes.IndexByte(data, 0) >= 0 || !utf8.Valid(data)
+}
@@ -0,0 +1,217 @@
+package txtar_test
+
+import (
+	"errors"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"go.followtheprocess.codes/test"
+	"go.followtheprocess.codes/txtar"
+)
+
+func TestFromFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.go":              {Data: []byte("package main\n")},
+		"a.txt":                {Data: []byte("  a\n\n")},
+		"cmd/tool/main.go":     {Data: []byte("package main")},
+		"cmd/tool/README.md":   {Data: []byte("# Tool")},
+		"internal/x/x.go":      {Data: []byte("package x")},
+		"internal/x/x_test.go": {Data: []byte("package x_test")},
+		"vendor/dep/dep.go":    {Data: []byte("package dep")},
+		"image.png":            {Data: []byte("\x89PNG\x00\x00")},
+		"big.txt":              {Data: []byte("this file is quite big")},
+	}
+
+	tests := []struct {
+		name    string             // Name of the test case
+		options []txtar.FromOption // Options to pass to FromFS
+		want    []string           // Expected file names, sorted
+		wantErr error              // Expected error, if any
+	}{
+		{
+			name:    "binary is an error by default",
+			options: nil,
+			wantErr: txtar.ErrBinaryFile,
+		},
+		{
+			name:    "skip binary",
+			options: []txtar.FromOption{txtar.WithBinary(txtar.BinarySkip)},
+			want: []string{
+				"a.txt",
+				"big.txt",
+				"cmd/tool/README.md",
+				"cmd/tool/main.go",
+				"internal/x/x.go",
+				"internal/x/x_test.go",
+				"main.go",
+				"vendor/dep/dep.go",
+			},
+		},
+		{
+			name:    "include",
+			options: []txtar.FromOption{txtar.WithInclude("*.go")},
+			want: []string{
+				"cmd/tool/main.go",
+				"internal/x/x.go",
+				"internal/x/x_test.go",
+				"main.go",
+				"vendor/dep/dep.go",
+			},
+		},
+		{
+			name: "include and exclude",
+			options: []txtar.FromOption{
+				txtar.WithInclude("*.go"),
+				txtar.WithExclude("vendor", "*_test.go"),
+			},
+			want: []string{
+				"cmd/tool/main.go",
+				"internal/x/x.go",
+				"main.go",
+			},
+		},
+		{
+			name:    "include full path",
+			options: []txtar.FromOption{txtar.WithInclude("cmd/*/*")},
+			want: []string{
+				"cmd/tool/README.md",
+				"cmd/tool/main.go",
+			},
+		},
+		{
+			name: "max size",
+			options: []txtar.FromOption{
+				txtar.WithBinary(txtar.BinarySkip),
+				txtar.WithMaxSize(20),
+			},
+			wantErr: txtar.ErrFileTooLarge,
+		},
+		{
+			name: "max size excluded",
+			options: []txtar.FromOption{
+				txtar.WithMaxSize(20),
+				txtar.WithInclude("*.txt"),
+				txtar.WithExclude("big.txt"),
+			},
+			want: []string{"a.txt"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			archive, err := txtar.FromFS(fsys, tt.options...)
+			if tt.wantErr != nil {
+				test.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			test.Ok(t, err)
+
+			got := slices.Collect(maps.Keys(maps.Collect(archive.Files())))
+			slices.Sort(got)
+			test.EqualFunc(t, got, tt.want, slices.Equal)
+
+			// Files should be in sorted order in the archive itself
+			var order []string
+			for name := range archive.Files() {
+				order = append(order, name)
+			}
+
+			test.True(t, slices.IsSorted(order), test.Context("Files not in sorted order: %v", order))
+		})
+	}
+}
+
+func TestFromFSContents(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.txt": {Data: []byte("\n\n  a  \n\n")},
+	}
+
+	archive, err := txtar.FromFS(fsys)
+	test.Ok(t, err)
+
+	contents, ok := archive.Read("a.txt")
+	test.True(t, ok)
+	test.Equal(t, contents, "a\n")
+}
+
+func TestFromFSMarkers(t *testing.T) {
+	fixture := "-- a.txt --\na\n"
+	fsys := fstest.MapFS{
+		"testdata/fixture.txtar": {Data: []byte(fixture)},
+	}
+
+	_, err := txtar.FromFS(fsys)
+	test.ErrorAs[*txtar.MarkerError](t, err)
+
+	archive, err := txtar.FromFS(fsys, txtar.WithMarkers(txtar.MarkerQuote))
+	test.Ok(t, err)
+
+	contents, ok := archive.Read("testdata/fixture.txtar")
+	test.True(t, ok)
+	test.Equal(t, contents, fixture)
+
+	// The fixture survives being written out and parsed back
+	reparsed, err := txtar.ParseWith(strings.NewReader(archive.String()), txtar.Quoted())
+	test.Ok(t, err)
+	test.Equal(t, reparsed.Size(), 1)
+
+	contents, ok = reparsed.Read("testdata/fixture.txtar")
+	test.True(t, ok)
+	test.Equal(t, contents, fixture)
+}
+
+func TestFromFSBadOptions(t *testing.T) {
+	_, err := txtar.FromFS(
+		fstest.MapFS{},
+		txtar.WithInclude("["),
+		txtar.WithExclude("[]"),
+		txtar.WithMaxSize(-1),
+		txtar.WithBinary(42),
+		txtar.WithMarkers(42),
+	)
+	test.Err(t, err)
+}
+
+func TestFromFSReportsAllErrors(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.txt":   {Data: []byte("-- not a file --\n")},
+		"b.txt":   {Data: []byte("fine")},
+		"c.txtar": {Data: []byte("-- c.txt --\nc\n")},
+	}
+
+	_, err := txtar.FromFS(fsys)
+	test.Err(t, err)
+	test.ErrorAs[*txtar.MarkerError](t, err)
+
+	// Both bad files are reported, not just the first
+	test.True(t, strings.Contains(err.Error(), `"a.txt"`), test.Context("a.txt not reported: %v", err))
+	test.True(t, strings.Contains(err.Error(), `"c.txtar"`), test.Context("c.txtar not reported: %v", err))
+}
+
+func TestFromDirExtractRoundTrip(t *testing.T) {
+	archive, err := txtar.New(
+		txtar.WithFile("top.txt", "top level"),
+		txtar.WithFile("a/b/c.go", "package b"),
+		txtar.WithFile("a/d.txt", "d"),
+	)
+	test.Ok(t, err)
+
+	dir := t.TempDir()
+	test.Ok(t, archive.Extract(dir))
+
+	got, err := txtar.FromDir(dir)
+	test.Ok(t, err)
+
+	// FromDir sorts, so compare as sets
+	test.EqualFunc(t, maps.Collect(got.Files()), maps.Collect(archive.Files()), maps.Equal)
+}
+
+func TestFromDirMissing(t *testing.T) {
+	_, err := txtar.FromDir("definitely/does/not/exist")
+	test.Err(t, err)
+	test.False(t, errors.Is(err, txtar.ErrBinaryFile))
+}
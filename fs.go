@@ -0,0 +1,336 @@
+package txtar
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	fileMode = 0o444              // Mode of files in the archive FS, read only
+	dirMode  = fs.ModeDir | 0o555 // Mode of directories in the archive FS, read only
+)
+
+// errIsDir is returned when attempting to read a directory as a file.
+var errIsDir = errors.New("is a directory")
+
+// Compile time interface checks.
+var (
+	_ fs.FS         = (*archiveFS)(nil)
+	_ fs.ReadFileFS = (*archiveFS)(nil)
+	_ fs.ReadDirFS  = (*archiveFS)(nil)
+	_ fs.StatFS     = (*archiveFS)(nil)
+	_ fs.GlobFS     = (*archiveFS)(nil)
+	_ fs.SubFS      = (*archiveFS)(nil)
+)
+
+// FS returns a read-only view of the archive as an [fs.FS].
+//
+// Slash separated file names like "a/b/c.go" are presented as a tree, with the
+// intermediate directories synthesised as needed. The returned value also implements
+// [fs.ReadFileFS], [fs.ReadDirFS], [fs.StatFS], [fs.GlobFS] and [fs.SubFS].
+//
+// The FS is a snapshot of the archive at the time FS is called, subsequent
+// modifications to the archive are not reflected in it.
+//
+// Files whose names are not valid [fs.FS] paths (see [fs.ValidPath]) are omitted, as
+// are files whose name is already in use as a directory (or vice versa), in which case
+// the first one added to the archive wins.
+func (a *Archive) FS() fs.FS {
+	root := &fsNode{name: ".", dir: true}
+	nodes := map[string]*fsNode{".": root}
+
+	if a == nil {
+		return &archiveFS{nodes: nodes}
+	}
+
+	for _, file := range a.files {
+		if file.name == "." || !fs.ValidPath(file.name) {
+			continue
+		}
+
+		if _, exists := nodes[file.name]; exists {
+			continue
+		}
+
+		parent := mkdirAll(nodes, path.Dir(file.name))
+		if parent == nil {
+			// One of the parents is a file
+			continue
+		}
+
+		node := &fsNode{name: path.Base(file.name), contents: file.contents}
+		nodes[file.name] = node
+		parent.children = append(parent.children, node)
+	}
+
+	for _, node := range nodes {
+		slices.SortFunc(node.children, func(a, b *fsNode) int { return strings.Compare(a.name, b.name) })
+	}
+
+	return &archiveFS{nodes: nodes}
+}
+
+// mkdirAll ensures the directory dir and all its parents exist in nodes, returning
+// the node for dir, or nil if dir or one of its parents is already a file.
+func mkdirAll(nodes map[string]*fsNode, dir string) *fsNode {
+	if node, exists := nodes[dir]; exists {
+		if !node.dir {
+			return nil
+		}
+
+		return node
+	}
+
+	parent := mkdirAll(nodes, path.Dir(dir))
+	if parent == nil {
+		return nil
+	}
+
+	node := &fsNode{name: path.Base(dir), dir: true}
+	nodes[dir] = node
+	parent.children = append(parent.children, node)
+
+	return node
+}
+
+// archiveFS is the [fs.FS] implementation returned from [Archive.FS].
+type archiveFS struct {
+	nodes map[string]*fsNode // Every file and directory, keyed by its full path, the root is "."
+}
+
+// Open implements [fs.FS] for an archiveFS.
+func (f *archiveFS) Open(name string) (fs.File, error) {
+	node, err := f.lookup("open", name)
+	if err != nil {
+		return nil, err
+	}
+
+	if node.dir {
+		return &openDir{node: node}, nil
+	}
+
+	return &openFile{node: node, Reader: strings.NewReader(node.contents)}, nil
+}
+
+// ReadFile implements [fs.ReadFileFS] for an archiveFS.
+func (f *archiveFS) ReadFile(name string) ([]byte, error) {
+	node, err := f.lookup("read", name)
+	if err != nil {
+		return nil, err
+	}
+
+	if node.dir {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: errIsDir}
+	}
+
+	return []byte(node.contents), nil
+}
+
+// ReadDir implements [fs.ReadDirFS] for an archiveFS.
+func (f *archiveFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	node, err := f.lookup("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+
+	if !node.dir {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+
+	return node.entries(), nil
+}
+
+// Stat implements [fs.StatFS] for an archiveFS.
+func (f *archiveFS) Stat(name string) (fs.FileInfo, error) {
+	return f.lookup("stat", name)
+}
+
+// Glob implements [fs.GlobFS] for an archiveFS.
+func (f *archiveFS) Glob(pattern string) ([]string, error) {
+	// Check the pattern is well formed
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+
+	var matches []string
+	for name := range f.nodes {
+		if name == "." && pattern != "." {
+			continue
+		}
+
+		if ok, _ := path.Match(pattern, name); ok {
+			matches = append(matches, name)
+		}
+	}
+
+	slices.Sort(matches)
+
+	return matches, nil
+}
+
+// Sub implements [fs.SubFS] for an archiveFS.
+func (f *archiveFS) Sub(dir string) (fs.FS, error) {
+	node, err := f.lookup("sub", dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if !node.dir {
+		return nil, &fs.PathError{Op: "sub", Path: dir, Err: fs.ErrInvalid}
+	}
+
+	if dir == "." {
+		return f, nil
+	}
+
+	// The new root is a copy of the sub directory but named "."
+	root := &fsNode{name: ".", dir: true, children: node.children}
+	nodes := map[string]*fsNode{".": root}
+
+	prefix := dir + "/"
+	for name, node := range f.nodes {
+		if rest, ok := strings.CutPrefix(name, prefix); ok {
+			nodes[rest] = node
+		}
+	}
+
+	return &archiveFS{nodes: nodes}, nil
+}
+
+// lookup finds the node with the given name, returning an [fs.PathError] for op
+// if the name is invalid or it doesn't exist.
+func (f *archiveFS) lookup(op, name string) (*fsNode, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+
+	node, ok := f.nodes[name]
+	if !ok {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+
+	return node, nil
+}
+
+// fsNode is a file or directory in an archiveFS, it implements both [fs.FileInfo]
+// and [fs.DirEntry].
+type fsNode struct {
+	name     string    // Base name of the file or directory
+	contents string    // Contents of a file, empty for directories
+	children []*fsNode // Directory entries sorted by name, nil for files
+	dir      bool      // Whether this is a directory
+}
+
+// entries returns the node's children as directory entries.
+func (n *fsNode) entries() []fs.DirEntry {
+	entries := make([]fs.DirEntry, 0, len(n.children))
+	for _, child := range n.children {
+		entries = append(entries, child)
+	}
+
+	return entries
+}
+
+// Name implements [fs.FileInfo] and [fs.DirEntry] for an fsNode.
+func (n *fsNode) Name() string {
+	return n.name
+}
+
+// Size implements [fs.FileInfo] for an fsNode.
+func (n *fsNode) Size() int64 {
+	return int64(len(n.contents))
+}
+
+// Mode implements [fs.FileInfo] for an fsNode.
+func (n *fsNode) Mode() fs.FileMode {
+	if n.dir {
+		return dirMode
+	}
+
+	return fileMode
+}
+
+// ModTime implements [fs.FileInfo] for an fsNode, txtar archives do not store
+// modification times so this is always the zero [time.Time].
+func (n *fsNode) ModTime() time.Time {
+	return time.Time{}
+}
+
+// IsDir implements [fs.FileInfo] and [fs.DirEntry] for an fsNode.
+func (n *fsNode) IsDir() bool {
+	return n.dir
+}
+
+// Sys implements [fs.FileInfo] for an fsNode.
+func (n *fsNode) Sys() any {
+	return nil
+}
+
+// Type implements [fs.DirEntry] for an fsNode.
+func (n *fsNode) Type() fs.FileMode {
+	return n.Mode().Type()
+}
+
+// Info implements [fs.DirEntry] for an fsNode.
+func (n *fsNode) Info() (fs.FileInfo, error) {
+	return n, nil
+}
+
+// openFile is an open file in an archiveFS.
+type openFile struct {
+	node            *fsNode
+	*strings.Reader // Implements Read, ReadAt and Seek
+}
+
+// Stat implements [fs.File] for an openFile.
+func (f *openFile) Stat() (fs.FileInfo, error) {
+	return f.node, nil
+}
+
+// Close implements [fs.File] for an openFile.
+func (f *openFile) Close() error {
+	return nil
+}
+
+// openDir is an open directory in an archiveFS.
+type openDir struct {
+	node   *fsNode
+	offset int // Number of entries already returned by ReadDir
+}
+
+// Stat implements [fs.File] for an openDir.
+func (d *openDir) Stat() (fs.FileInfo, error) {
+	return d.node, nil
+}
+
+// Read implements [fs.File] for an openDir, it always fails.
+func (d *openDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.node.name, Err: errIsDir}
+}
+
+// Close implements [fs.File] for an openDir.
+func (d *openDir) Close() error {
+	return nil
+}
+
+// ReadDir implements [fs.ReadDirFile] for an openDir.
+func (d *openDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	entries := d.node.entries()[d.offset:]
+
+	if n > 0 {
+		if len(entries) == 0 {
+			return nil, io.EOF
+		}
+
+		entries = entries[:min(n, len(entries))]
+	}
+
+	d.offset += len(entries)
+
+	return entries, nil
+}
@@ -0,0 +1,109 @@
+package txtar_test
+
+import (
+	"errors"
+	"io/fs"
+	"slices"
+	"testing"
+	"testing/fstest"
+
+	"go.followtheprocess.codes/test"
+	"go.followtheprocess.codes/txtar"
+)
+
+func TestFS(t *testing.T) {
+	archive, err := txtar.New(
+		txtar.WithComment("The comment isn't part of the FS"),
+		txtar.WithFile("top.txt", "top level"),
+		txtar.WithFile("a/b/c.go", "package b"),
+		txtar.WithFile("a/b/d.go", "package b"),
+		txtar.WithFile("a/e.txt", "e"),
+		txtar.WithFile("empty.txt", ""),
+		txtar.WithFile("z/y/x/deep.txt", "deep"),
+	)
+	test.Ok(t, err)
+
+	fsys := archive.FS()
+
+	err = fstest.TestFS(fsys, "top.txt", "a/b/c.go", "a/b/d.go", "a/e.txt", "empty.txt", "z/y/x/deep.txt")
+	test.Ok(t, err)
+
+	contents, err := fs.ReadFile(fsys, "a/b/c.go")
+	test.Ok(t, err)
+	test.Equal(t, string(contents), "package b\n")
+
+	info, err := fs.Stat(fsys, "a/b")
+	test.Ok(t, err)
+	test.True(t, info.IsDir(), test.Context("a/b should be a directory"))
+
+	entries, err := fs.ReadDir(fsys, ".")
+	test.Ok(t, err)
+
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+
+	test.EqualFunc(t, names, []string{"a", "empty.txt", "top.txt", "z"}, slices.Equal)
+
+	matches, err := fs.Glob(fsys, "a/*/*.go")
+	test.Ok(t, err)
+	test.EqualFunc(t, matches, []string{"a/b/c.go", "a/b/d.go"}, slices.Equal)
+
+	_, err = fs.Glob(fsys, "[")
+	test.Err(t, err)
+
+	sub, err := fs.Sub(fsys, "a")
+	test.Ok(t, err)
+	test.Ok(t, fstest.TestFS(sub, "b/c.go", "b/d.go", "e.txt"))
+
+	_, err = fs.Sub(fsys, "top.txt")
+	test.Err(t, err)
+
+	_, err = fs.ReadFile(fsys, "a")
+	test.Err(t, err)
+
+	_, err = fsys.Open("missing.txt")
+	test.True(t, errors.Is(err, fs.ErrNotExist), test.Context("Expected ErrNotExist, got %v", err))
+}
+
+func TestFSSnapshot(t *testing.T) {
+	archive, err := txtar.New(txtar.WithFile("file.txt", "before"))
+	test.Ok(t, err)
+
+	fsys := archive.FS()
+
+	test.Ok(t, archive.Write("file.txt", "after"))
+	test.Ok(t, archive.Write("new.txt", "new"))
+
+	contents, err := fs.ReadFile(fsys, "file.txt")
+	test.Ok(t, err)
+	test.Equal(t, string(contents), "before\n")
+
+	_, err = fs.Stat(fsys, "new.txt")
+	test.True(t, errors.Is(err, fs.ErrNotExist), test.Context("FS should not see files written after it was created"))
+}
+
+func TestFSInvalidNames(t *testing.T) {
+	archive, err := txtar.New(
+		txtar.WithFile("ok.txt", "fine"),
+		txtar.WithFile("/abs.txt", "absolute"),
+		txtar.WithFile("../escape.txt", "escape"),
+		txtar.WithFile("a//b.txt", "double slash"),
+		txtar.WithFile("conflict", "a file"),
+		txtar.WithFile("conflict/child.txt", "can't be a child of a file"),
+	)
+	test.Ok(t, err)
+
+	fsys := archive.FS()
+	test.Ok(t, fstest.TestFS(fsys, "ok.txt", "conflict"))
+
+	info, err := fs.Stat(fsys, "conflict")
+	test.Ok(t, err)
+	test.False(t, info.IsDir(), test.Context("conflict should be a file as it came first"))
+}
+
+func TestFSNil(t *testing.T) {
+	var archive *txtar.Archive
+	test.Ok(t, fstest.TestFS(archive.FS()))
+}
@@ -3,12 +3,12 @@ module go.followtheprocess.codes/txtar
 go 1.26
 
 require (
+	go.followtheprocess.codes/diff v0.2.0
 	go.followtheprocess.codes/test v1.4.0
 	golang.org/x/tools v0.45.0
 )
 
 require (
-	go.followtheprocess.codes/diff v0.2.0 // indirect
 	go.followtheprocess.codes/hue v1.1.0 // indirect
 	golang.org/x/sys v0.44.0 // indirect
 	golang.org/x/term v0.42.0 // indirect
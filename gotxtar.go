@@ -0,0 +1,120 @@
+package txtar
+
+import (
+	"errors"
+
+	gotxtar "golang.org/x/tools/txtar"
+)
+
+// FromGoArchive builds an [Archive] from an archive of the original [golang.org/x/tools/txtar]
+// package, applying any number of options first, so code can be migrated incrementally.
+//
+// The options configure how the comment and files are stored, e.g. [WithVerbatim],
+// [WithNamePolicy] or [WithMarkerPolicy], and should not themselves add files. The
+// differences from the original are:
+//
+//   - Whitespace: by default the comment and file contents are trimmed and given a final
+//     newline (as by [Archive.Write]) whereas the original stores them exactly as given.
+//     With [WithVerbatim] they are stored exactly, other than adding a missing final newline
+//     as [gotxtar.Format] would, so the archive serialises to the same text.
+//   - Names: leading and trailing whitespace is trimmed (the original parser does this
+//     too but its Archive may be built by hand) and names are checked according to the
+//     [NamePolicy], unchecked by default.
+//   - Duplicates: the original permits several files with the same name. They are all kept,
+//     in order, exactly as [Parse] does with [DuplicateKeepAll], so [Archive.Read] returns the
+//     first and [Archive.Duplicates] the rest.
+//   - Markers: contents with lines that look like file markers, which the original would
+//     silently write out and then parse back as a different set of files, are rejected
+//     unless the [MarkerPolicy] is [MarkerQuote].
+//
+// As with [New], all the errors are reported together.
+//
+// [golang.org/x/tools/txtar]: https://pkg.go.dev/golang.org/x/tools/txtar
+func FromGoArchive(archive *gotxtar.Archive, options ...Option) (*Archive, error) {
+	if archive == nil {
+		return nil, errors.New("FromGoArchive: archive was nil")
+	}
+
+	a, err := New(options...)
+	if err != nil {
+		return nil, err
+	}
+
+	errs := WithComment(string(archive.Comment))(a)
+
+	for _, file := range archive.Files {
+		errs = errors.Join(errs, a.add(file.Name, string(file.Data)))
+	}
+
+	if errs != nil {
+		return nil, errs
+	}
+
+	return a, nil
+}
+
+// ToGoArchive converts the [Archive] to an archive of the original [golang.org/x/tools/txtar]
+// package, so it can be passed to code that has not been migrated yet.
+//
+// The result is exactly what [gotxtar.Parse] would return given the output of
+// [Archive.String], so [gotxtar.Format] produces the same text:
+//
+//   - The comment includes the blank line [Archive.String] writes between it and the first
+//     file (if the archive is not verbatim), and any file marker lines in the comment or
+//     contents are quoted according to the [MarkerPolicy].
+//   - Files are in the order [Archive.String] writes them, sorted if the archive was created
+//     with [WithSorted], and include any duplicates.
+//
+// The result shares no memory with the archive. Calling ToGoArchive on a nil archive
+// returns an empty one.
+//
+// [golang.org/x/tools/txtar]: https://pkg.go.dev/golang.org/x/tools/txtar
+func (a *Archive) ToGoArchive() *gotxtar.Archive {
+	if a == nil {
+		return &gotxtar.Archive{}
+	}
+
+	archive := &gotxtar.Archive{
+		Files: make([]gotxtar.File, 0, len(a.files)),
+	}
+
+	if header := a.header(); header != "" {
+		archive.Comment = []byte(header)
+	}
+
+	for _, file := range a.ordered(a.sorted) {
+		archive.Files = append(archive.Files, gotxtar.File{
+			Name: file.name,
+			Data: []byte(a.quote(file.contents)),
+		})
+	}
+
+	return archive
+}
+
+// add adds a file to the archive exactly as [Archive.Write] does, except that a file
+// with the same name as an existing one is added as a duplicate rather than replacing it.
+func (a *Archive) add(name, contents string) error {
+	name, err := a.checkName(name)
+	if err != nil {
+		return err
+	}
+
+	contents = a.normalise(contents)
+
+	if err := a.checkMarkers(name, contents); err != nil {
+		return err
+	}
+
+	if a.index == nil {
+		a.index = make(map[string]int)
+	}
+
+	if _, ok := a.find(name); !ok {
+		a.index[name] = len(a.files)
+	}
+
+	a.files = append(a.files, file{name: name, contents: contents})
+
+	return nil
+}
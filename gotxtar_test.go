@@ -0,0 +1,147 @@
+package txtar_test
+
+import (
+	"bytes"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"go.followtheprocess.codes/test"
+	"go.followtheprocess.codes/txtar"
+	gotxtar "golang.org/x/tools/txtar"
+)
+
+func TestGoArchiveRoundTrip(t *testing.T) {
+	var files []string
+	for _, dir := range []string{"TestCompat", filepath.Join("TestParse", "valid"), filepath.Join("TestParse", "invalid"), filepath.Join("TestParse", "duplicate")} {
+		matches, err := filepath.Glob(filepath.Join("testdata", dir, "*.txtar"))
+		test.Ok(t, err, test.Context("Could not glob the %s directory", dir))
+
+		files = append(files, matches...)
+	}
+
+	modes := []struct {
+		name    string              // Name of the mode
+		parse   []txtar.ParseOption // Options to parse the file with
+		options []txtar.Option      // Equivalent options for FromGoArchive
+	}{
+		{name: "default", parse: []txtar.ParseOption{txtar.Lenient()}},
+		{
+			name:    "verbatim",
+			parse:   []txtar.ParseOption{txtar.Lenient(), txtar.Verbatim()},
+			options: []txtar.Option{txtar.WithVerbatim()},
+		},
+	}
+
+	for _, mode := range modes {
+		for _, file := range files {
+			t.Run(mode.name+"/"+filepath.ToSlash(file), func(t *testing.T) {
+				contents, err := os.ReadFile(file)
+				test.Ok(t, err)
+
+				contents = bytes.ReplaceAll(contents, []byte("\r\n"), []byte("\n"))
+
+				ours, err := txtar.ParseWith(bytes.NewReader(contents), mode.parse...)
+				test.Ok(t, err)
+
+				// Converting is the same as parsing leniently
+				converted, err := txtar.FromGoArchive(gotxtar.Parse(contents), mode.options...)
+				test.Ok(t, err)
+				test.True(t, txtar.Equal(converted, ours), test.Context("Mismatch: %v", txtar.Mismatch(converted, ours)))
+
+				// And back is the same as x/tools parsing what we'd write
+				goArchive := ours.ToGoArchive()
+				test.Diff(t, string(gotxtar.Format(goArchive)), ours.String())
+
+				want := gotxtar.Parse([]byte(ours.String()))
+				test.Equal(t, string(goArchive.Comment), string(want.Comment))
+				test.Equal(t, len(goArchive.Files), len(want.Files))
+
+				for i := range min(len(goArchive.Files), len(want.Files)) {
+					test.Equal(t, goArchive.Files[i].Name, want.Files[i].Name)
+					test.Equal(t, string(goArchive.Files[i].Data), string(want.Files[i].Data))
+				}
+			})
+		}
+	}
+}
+
+func TestFromGoArchive(t *testing.T) {
+	goArchive := &gotxtar.Archive{
+		Comment: []byte("  comment  \n"),
+		Files: []gotxtar.File{
+			{Name: " a.txt ", Data: []byte("first\n")},
+			{Name: "b.txt", Data: []byte("  b  ")},
+			{Name: "a.txt", Data: []byte("second\n")},
+		},
+	}
+
+	archive, err := txtar.FromGoArchive(goArchive)
+	test.Ok(t, err)
+
+	test.Equal(t, archive.Comment(), "comment")
+	test.Equal(t, archive.Size(), 3)
+
+	contents, ok := archive.Read("a.txt")
+	test.True(t, ok)
+	test.Equal(t, contents, "first\n")
+
+	duplicates := maps.Collect(archive.Duplicates())
+	test.EqualFunc(t, duplicates, map[string]string{"a.txt": "second\n"}, maps.Equal)
+
+	// Verbatim keeps the whitespace
+	archive, err = txtar.FromGoArchive(goArchive, txtar.WithVerbatim())
+	test.Ok(t, err)
+	test.Equal(t, archive.Comment(), "  comment  \n")
+
+	contents, ok = archive.Read("b.txt")
+	test.True(t, ok)
+	test.Equal(t, contents, "  b  \n")
+
+	// The original would happily write this and parse back two files
+	marker := &gotxtar.Archive{Files: []gotxtar.File{{Name: "a.txt", Data: []byte("-- b.txt --\n")}}}
+
+	_, err = txtar.FromGoArchive(marker)
+	test.ErrorAs[*txtar.MarkerError](t, err)
+
+	archive, err = txtar.FromGoArchive(marker, txtar.WithMarkerPolicy(txtar.MarkerQuote))
+	test.Ok(t, err)
+	test.Equal(t, len(archive.ToGoArchive().Files), 1)
+	test.Equal(t, len(gotxtar.Parse(gotxtar.Format(archive.ToGoArchive())).Files), 1)
+
+	// Names are checked
+	_, err = txtar.FromGoArchive(
+		&gotxtar.Archive{Files: []gotxtar.File{{Name: "../escape.txt"}}},
+		txtar.WithNamePolicy(txtar.NameClean),
+	)
+	test.ErrorIs(t, err, txtar.ErrEscapingName)
+
+	_, err = txtar.FromGoArchive(nil)
+	test.Err(t, err)
+}
+
+func TestToGoArchive(t *testing.T) {
+	archive, err := txtar.New(
+		txtar.WithSorted(),
+		txtar.WithComment("comment"),
+		txtar.WithFile("b.txt", "b"),
+		txtar.WithFile("a.txt", "a"),
+	)
+	test.Ok(t, err)
+
+	goArchive := archive.ToGoArchive()
+	test.Equal(t, string(goArchive.Comment), "comment\n\n")
+	test.EqualFunc(t, []string{goArchive.Files[0].Name, goArchive.Files[1].Name}, []string{"a.txt", "b.txt"}, slices.Equal)
+
+	// Modifying the result doesn't affect the archive
+	goArchive.Files[0].Data[0] = 'X'
+
+	contents, ok := archive.Read("a.txt")
+	test.True(t, ok)
+	test.Equal(t, contents, "a\n")
+
+	var missing *txtar.Archive
+	test.Equal(t, len(missing.ToGoArchive().Files), 0)
+}
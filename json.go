@@ -0,0 +1,108 @@
+package txtar
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+)
+
+// Compile time interface checks.
+var (
+	_ json.Marshaler   = (*Archive)(nil)
+	_ json.Unmarshaler = (*Archive)(nil)
+)
+
+// jsonArchive is the JSON representation of an [Archive].
+type jsonArchive struct {
+	Comment string     `json:"comment"`
+	Files   []jsonFile `json:"files"`
+}
+
+// jsonFile is the JSON representation of a single file in an [Archive].
+type jsonFile struct {
+	Name     string `json:"name"`
+	Contents string `json:"contents"`
+}
+
+// MarshalJSON implements [json.Marshaler] for an [Archive], using the schema:
+//
+//	{
+//	  "comment": "The archive comment",
+//	  "files": [
+//	    {"name": "a.txt", "contents": "File contents\n"},
+//	    {"name": "b.txt", "contents": "More contents\n"}
+//	  ]
+//	}
+//
+// Both keys are always present, "files" being an empty array rather than null for an
+// archive with no files. Files appear in archive order (not sorted, even if the archive
+// was created with [WithSorted]) and include any duplicates (see [DuplicateKeepAll]).
+//
+// Comments and contents are exactly as stored in the archive, i.e. as returned by
+// [Archive.Comment] and [Archive.Read], with any [MarkerQuote] quoting not applied.
+//
+// This takes precedence over [Archive.MarshalText] in encoding/json, marshal the result
+// of [Archive.String] to embed the archive as a single string instead.
+func (a *Archive) MarshalJSON() ([]byte, error) {
+	if a == nil {
+		return []byte("null"), nil
+	}
+
+	doc := jsonArchive{
+		Comment: a.comment,
+		Files:   make([]jsonFile, 0, len(a.files)),
+	}
+
+	for _, file := range a.files {
+		doc.Files = append(doc.Files, jsonFile{Name: file.name, Contents: file.contents})
+	}
+
+	return json.Marshal(doc)
+}
+
+// UnmarshalJSON implements [json.Unmarshaler] for an [Archive], replacing its comment
+// and files with those in data, which must follow the schema documented on
+// [Archive.MarshalJSON]. Missing keys are treated as empty and unknown keys are ignored.
+//
+// The comment and files are added as if by [WithComment] and [Archive.Write] so
+// are subject to the archive's own whitespace handling, [NamePolicy] and [MarkerPolicy].
+// Files with duplicate names are all kept in order, as [DuplicateKeepAll] does, so
+// that any archive survives the round trip through [Archive.MarshalJSON].
+//
+// If data is invalid, the error is returned and the archive left untouched. As is
+// conventional, the JSON null is a no-op.
+func (a *Archive) UnmarshalJSON(data []byte) error {
+	if a == nil {
+		return errors.New("UnmarshalJSON called on a nil Archive")
+	}
+
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		return nil
+	}
+
+	var doc jsonArchive
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+
+	work := &Archive{
+		markers:  a.markers,
+		names:    a.names,
+		verbatim: a.verbatim,
+		sorted:   a.sorted,
+	}
+
+	errs := WithComment(doc.Comment)(work)
+
+	for _, file := range doc.Files {
+		errs = errors.Join(errs, work.add(file.Name, file.Contents))
+	}
+
+	if errs != nil {
+		return errs
+	}
+
+	*a = *work
+
+	return nil
+}
@@ -0,0 +1,212 @@
+package txtar_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.followtheprocess.codes/test"
+	"go.followtheprocess.codes/txtar"
+)
+
+func TestMarshalJSON(t *testing.T) {
+	archive, err := txtar.New(
+		txtar.WithComment("A comment"),
+		txtar.WithFile("z.txt", "last alphabetically"),
+		txtar.WithFile("a/b.txt", "with \"quotes\"\nand lines"),
+		txtar.WithFile("empty.txt", ""),
+	)
+	test.Ok(t, err)
+
+	got, err := json.MarshalIndent(archive, "", "  ")
+	test.Ok(t, err)
+
+	want := `{
+  "comment": "A comment",
+  "files": [
+    {
+      "name": "z.txt",
+      "contents": "last alphabetically\n"
+    },
+    {
+      "name": "a/b.txt",
+      "contents": "with \"quotes\"\nand lines\n"
+    },
+    {
+      "name": "empty.txt",
+      "contents": ""
+    }
+  ]
+}`
+	test.Diff(t, string(got), want)
+
+	// Files is never null
+	got, err = json.Marshal(&txtar.Archive{})
+	test.Ok(t, err)
+	test.Equal(t, string(got), `{"comment":"","files":[]}`)
+
+	// Nil is null, like any other pointer
+	var missing *txtar.Archive
+	got, err = json.Marshal(struct{ Archive *txtar.Archive }{Archive: missing})
+	test.Ok(t, err)
+	test.Equal(t, string(got), `{"Archive":null}`)
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	documents := map[string]string{
+		"duplicates":   "-- a.txt --\nfirst\n-- b.txt --\nb\n-- a.txt --\nsecond\n",
+		"comment only": "just a comment\n",
+	}
+
+	for _, dir := range []string{"TestCompat", filepath.Join("TestParse", "valid")} {
+		matches, err := filepath.Glob(filepath.Join("testdata", dir, "*.txtar"))
+		test.Ok(t, err, test.Context("Could not glob the %s directory", dir))
+
+		for _, match := range matches {
+			contents, err := os.ReadFile(match)
+			test.Ok(t, err)
+
+			documents[filepath.ToSlash(match)] = string(contents)
+		}
+	}
+
+	modes := []struct {
+		name    string              // Name of the mode
+		parse   []txtar.ParseOption // Options to parse the document with
+		options []txtar.Option      // Options for the archive to unmarshal the JSON into
+	}{
+		{name: "default", parse: []txtar.ParseOption{txtar.Lenient()}},
+		{
+			name:    "verbatim",
+			parse:   []txtar.ParseOption{txtar.Lenient(), txtar.Verbatim()},
+			options: []txtar.Option{txtar.WithVerbatim()},
+		},
+	}
+
+	for _, mode := range modes {
+		for name, contents := range documents {
+			t.Run(mode.name+"/"+name, func(t *testing.T) {
+				original, err := txtar.ParseWith(strings.NewReader(contents), mode.parse...)
+				test.Ok(t, err)
+
+				// JSON -> Archive
+				data, err := json.Marshal(original)
+				test.Ok(t, err)
+
+				fromJSON, err := txtar.New(mode.options...)
+				test.Ok(t, err)
+				test.Ok(t, json.Unmarshal(data, fromJSON))
+				test.True(t, txtar.Equal(fromJSON, original), test.Context("Archive from JSON differs from the original"))
+
+				// Archive -> txtar -> Archive
+				reparsed, err := txtar.ParseWith(strings.NewReader(fromJSON.String()), mode.parse...)
+				test.Ok(t, err)
+				test.True(t, txtar.Equal(reparsed, fromJSON), test.Context("Reparsed archive differs from the one built from JSON"))
+
+				// And back to the exact same JSON
+				again, err := json.Marshal(reparsed)
+				test.Ok(t, err)
+				test.Diff(t, string(again), string(data))
+			})
+		}
+	}
+}
+
+func TestUnmarshalJSON(t *testing.T) {
+	t.Run("embedded", func(t *testing.T) {
+		var config struct {
+			Fixture *txtar.Archive `json:"fixture"`
+			Name    string         `json:"name"`
+		}
+
+		data := `{"name": "test", "fixture": {"comment": "  trimmed  ", "files": [{"name": "b.txt", "contents": "b"}, {"name": "a.txt"}]}}`
+		test.Ok(t, json.Unmarshal([]byte(data), &config))
+
+		test.Equal(t, config.Name, "test")
+		test.Diff(t, config.Fixture.String(), "trimmed\n\n-- b.txt --\nb\n-- a.txt --\n")
+	})
+
+	t.Run("missing keys", func(t *testing.T) {
+		archive := &txtar.Archive{}
+		test.Ok(t, json.Unmarshal([]byte(`{"unknown": true}`), archive))
+		test.Equal(t, archive.Size(), 0)
+		test.Equal(t, archive.Comment(), "")
+	})
+
+	t.Run("null", func(t *testing.T) {
+		archive, err := txtar.New(txtar.WithFile("a.txt", "a"))
+		test.Ok(t, err)
+		test.Ok(t, archive.UnmarshalJSON([]byte("null")))
+		test.True(t, archive.Has("a.txt"))
+	})
+
+	t.Run("duplicates", func(t *testing.T) {
+		archive, err := txtar.New(txtar.WithNamePolicy(txtar.NameClean))
+		test.Ok(t, err)
+
+		data := `{"files": [{"name": "a.txt", "contents": "one"}, {"name": " a.txt ", "contents": "two"}]}`
+		test.Ok(t, json.Unmarshal([]byte(data), archive))
+		test.Diff(t, archive.String(), "-- a.txt --\none\n-- a.txt --\ntwo\n")
+
+		contents, ok := archive.Read("a.txt")
+		test.True(t, ok)
+		test.Equal(t, contents, "one\n")
+	})
+
+	t.Run("marker policy", func(t *testing.T) {
+		archive, err := txtar.New(txtar.WithMarkerPolicy(txtar.MarkerQuote))
+		test.Ok(t, err)
+
+		data := `{"files": [{"name": "a.txt", "contents": "-- not a file --\n"}]}`
+		test.Ok(t, json.Unmarshal([]byte(data), archive))
+		test.Diff(t, archive.String(), "-- a.txt --\n\\-- not a file --\n")
+	})
+
+	failures := []struct {
+		wantErr error  // Expected error, nil means just any error
+		name    string // Name of the test case
+		data    string // JSON to unmarshal
+	}{
+		{
+			name:    "bad name",
+			data:    `{"files": [{"name": "../escape.txt", "contents": "one"}]}`,
+			wantErr: txtar.ErrEscapingName,
+		},
+		{
+			name: "marker in contents",
+			data: `{"files": [{"name": "a.txt", "contents": "-- b.txt --\n"}]}`,
+		},
+		{
+			name: "wrong type",
+			data: `{"files": {"name": "a.txt"}}`,
+		},
+		{
+			name: "invalid",
+			data: `{"comment":`,
+		},
+	}
+
+	for _, tt := range failures {
+		t.Run(tt.name, func(t *testing.T) {
+			archive, err := txtar.New(
+				txtar.WithNamePolicy(txtar.NameClean),
+				txtar.WithComment("original"),
+				txtar.WithFile("original.txt", "original"),
+			)
+			test.Ok(t, err)
+
+			before := archive.String()
+
+			err = archive.UnmarshalJSON([]byte(tt.data))
+			test.Err(t, err)
+
+			if tt.wantErr != nil {
+				test.ErrorIs(t, err, tt.wantErr)
+			}
+
+			test.Diff(t, archive.String(), before)
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package txtar
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MarkerPolicy determines what an [Archive] does with comments or file contents
+// containing lines that would be parsed as a file marker (e.g. "-- other.txt --").
+//
+// Left alone, such lines would cause the serialised archive to parse back into a
+// different set of files than it was created with.
+type MarkerPolicy int
+
+const (
+	// MarkerReject causes [Archive.Write], [WithFile] and [WithComment] to return
+	// a [*MarkerError] when given text containing a file marker line. This is the default.
+	MarkerReject MarkerPolicy = iota
+
+	// MarkerQuote allows text containing file marker lines and quotes them when the
+	// archive is serialised, by prefixing them with a backslash (e.g. "\-- other.txt --").
+	//
+	// Lines that already consist of one or more backslashes followed by a file marker get one
+	// more backslash so the scheme is fully reversible, use [Quoted] when parsing to undo it.
+	//
+	// Note that the original txtar package and tools built on it know nothing of this
+	// scheme and will see the quoted lines as is.
+	MarkerQuote
+)
+
+// MarkerError is the error returned when text added to an [Archive] contains a line
+// that would be parsed as a file marker, see [MarkerPolicy].
+type MarkerError struct {
+	Name   string // Name of the file whose contents contain the marker, empty for the archive comment
+	Marker string // The offending line
+	Line   int    // 1-based line number of the offending line within the comment or file contents
+}
+
+// Error implements the error interface for a [MarkerError].
+func (e *MarkerError) Error() string {
+	where := "comment"
+	if e.Name != "" {
+		where = fmt.Sprintf("file %q", e.Name)
+	}
+
+	return fmt.Sprintf("%s: line %d would be parsed as a file marker: %q", where, e.Line, e.Marker)
+}
+
+// WithMarkerPolicy is an [Option] that sets how an [Archive] handles comments and
+// file contents containing lines that look like file markers, see [MarkerPolicy].
+//
+// WithMarkerPolicy only affects comments and files added after it, so should be passed
+// to [New] before any [WithComment] or [WithFile].
+func WithMarkerPolicy(policy MarkerPolicy) Option {
+	return func(a *Archive) error {
+		if policy < MarkerReject || policy > MarkerQuote {
+			return fmt.Errorf("WithMarkerPolicy: invalid MarkerPolicy %d", policy)
+		}
+
+		a.markers = policy
+
+		return nil
+	}
+}
+
+// Quoted is a [ParseOption] that undoes the quoting applied by an archive with
+// the [MarkerQuote] policy, the resulting [Archive] also has that policy.
+func Quoted() ParseOption {
+	return func(cfg *parseConfig) error {
+		cfg.quoted = true
+
+		return nil
+	}
+}
+
+// checkMarkers returns a [*MarkerError] if the archive's policy is [MarkerReject]
+// and text contains a file marker line. name is the name of the file being checked,
+// or empty for the comment.
+func (a *Archive) checkMarkers(name, text string) error {
+	if a.markers != MarkerReject || !strings.Contains(text, string(marker)) {
+		return nil
+	}
+
+	number := 0
+	for line := range strings.Lines(text) {
+		number++
+
+		if isMarkerLine(line) {
+			return &MarkerError{Name: name, Line: number, Marker: strings.TrimRight(line, "\r\n")}
+		}
+	}
+
+	return nil
+}
+
+// quote applies the archive's [MarkerPolicy] to text on its way out.
+func (a *Archive) quote(text string) string {
+	if a.markers != MarkerQuote || !strings.Contains(text, string(marker)) {
+		return text
+	}
+
+	s := &strings.Builder{}
+	s.Grow(len(text))
+
+	for line := range strings.Lines(text) {
+		if isMarkerLine(strings.TrimLeft(line, `\`)) {
+			s.WriteByte('\\')
+		}
+
+		s.WriteString(line)
+	}
+
+	return s.String()
+}
+
+// unquote reverses [Archive.quote].
+func unquote(text string) string {
+	if !strings.Contains(text, `\`+string(marker)) {
+		return text
+	}
+
+	s := &strings.Builder{}
+	s.Grow(len(text))
+
+	for line := range strings.Lines(text) {
+		if strings.HasPrefix(line, `\`) && isMarkerLine(strings.TrimLeft(line, `\`)) {
+			line = line[1:]
+		}
+
+		s.WriteString(line)
+	}
+
+	return s.String()
+}
+
+// isMarkerLine reports whether a single line of text (with or without its line
+// ending) would be parsed as a file marker.
+func isMarkerLine(line string) bool {
+	if !strings.HasPrefix(line, string(marker)) {
+		return false
+	}
+
+	// Parsing normalises "\r\n" so we must too
+	line = strings.TrimSuffix(line, "\n")
+	line = strings.TrimSuffix(line, "\r")
+
+	name, _ := isMarker([]byte(line))
+
+	return name != ""
+}
@@ -0,0 +1,125 @@
+package txtar_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"go.followtheprocess.codes/test"
+	"go.followtheprocess.codes/txtar"
+)
+
+func TestMarkerReject(t *testing.T) {
+	tests := []struct {
+		name     string // Name of the test case
+		contents string // File contents to write
+		marker   string // Expected offending marker, empty if no error expected
+		line     int    // Expected line number of the marker
+	}{
+		{
+			name:     "no markers",
+			contents: "just some stuff\n",
+		},
+		{
+			name:     "almost markers",
+			contents: "-- nope\n --not at start --\nx -- y --\n-- --\n",
+		},
+		{
+			name:     "marker first line",
+			contents: "-- other.txt --\nstuff",
+			marker:   "-- other.txt --",
+			line:     1,
+		},
+		{
+			name:     "marker later",
+			contents: "some stuff\nmore stuff\n--   spaced.txt   --\n",
+			marker:   "--   spaced.txt   --",
+			line:     3,
+		},
+		{
+			name:     "marker with crlf",
+			contents: "stuff\r\n-- other.txt --\r\nmore",
+			marker:   "-- other.txt --",
+			line:     2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			archive, err := txtar.New()
+			test.Ok(t, err)
+
+			err = archive.Write("file.txt", tt.contents)
+			if tt.marker == "" {
+				test.Ok(t, err)
+				return
+			}
+
+			markerErr := test.ErrorAs[*txtar.MarkerError](t, err)
+			test.Equal(t, markerErr.Name, "file.txt")
+			test.Equal(t, markerErr.Marker, tt.marker)
+			test.Equal(t, markerErr.Line, tt.line)
+			test.False(t, archive.Has("file.txt"), test.Context("Rejected file should not have been written"))
+		})
+	}
+}
+
+func TestMarkerRejectOptions(t *testing.T) {
+	_, err := txtar.New(txtar.WithFile("file.txt", "-- other.txt --"))
+	test.ErrorAs[*txtar.MarkerError](t, err)
+
+	_, err = txtar.New(txtar.WithComment("A comment\n-- other.txt --\n"))
+	markerErr := test.ErrorAs[*txtar.MarkerError](t, err)
+	test.Equal(t, markerErr.Name, "", test.Context("Comment errors should have no Name"))
+	test.Equal(t, markerErr.Error(), `comment: line 2 would be parsed as a file marker: "-- other.txt --"`)
+
+	_, err = txtar.New(txtar.WithMarkerPolicy(42))
+	test.Err(t, err)
+}
+
+func TestMarkerQuote(t *testing.T) {
+	contents := "before\n-- other.txt --\n\\-- already quoted.txt --\n\\\\-- twice.txt --\n\\not a marker\nafter\n"
+
+	archive, err := txtar.New(
+		txtar.WithMarkerPolicy(txtar.MarkerQuote),
+		txtar.WithComment("A comment\n-- in the comment --"),
+		txtar.WithFile("file.txt", contents),
+		txtar.WithFile("other.txt", "other"),
+	)
+	test.Ok(t, err)
+
+	// Reading gives back the real contents
+	got, ok := archive.Read("file.txt")
+	test.True(t, ok)
+	test.Equal(t, got, contents)
+
+	want := `A comment
+\-- in the comment --
+
+-- file.txt --
+before
+\-- other.txt --
+\\-- already quoted.txt --
+\\\-- twice.txt --
+\not a marker
+after
+-- other.txt --
+other
+`
+	test.Diff(t, archive.String(), want)
+
+	buf := &bytes.Buffer{}
+	test.Ok(t, txtar.Dump(buf, archive))
+	test.Diff(t, buf.String(), want)
+
+	// A plain parse sees the quoted text but the right set of files
+	plain, err := txtar.Parse(strings.NewReader(archive.String()))
+	test.Ok(t, err)
+	test.Equal(t, plain.Size(), 2)
+
+	// And a quoted parse gets us back to where we started
+	reparsed, err := txtar.ParseWith(strings.NewReader(archive.String()), txtar.Quoted())
+	test.Ok(t, err)
+	test.True(t, txtar.Equal(archive, reparsed), test.Context("Quoted archive did not survive a round trip"))
+	test.Diff(t, reparsed.String(), want)
+}
@@ -0,0 +1,197 @@
+package txtar
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrMergeConflict is returned (wrapped) from [Archive.Merge] under [ConflictError] when
+// both archives contain a file with the same name but different contents.
+var ErrMergeConflict = errors.New("file exists in both archives with different contents")
+
+// ConflictPolicy determines what [Archive.Merge] does when both archives contain a
+// file with the same name but different contents.
+type ConflictPolicy int
+
+const (
+	// ConflictOverride replaces the existing file's contents with the incoming ones,
+	// so later layers win. This is the default.
+	ConflictOverride ConflictPolicy = iota
+
+	// ConflictKeep keeps the existing file's contents, ignoring the incoming ones.
+	ConflictKeep
+
+	// ConflictError causes Merge to return an error wrapping [ErrMergeConflict].
+	ConflictError
+)
+
+// CommentPolicy determines how [Archive.Merge] combines the two archive comments.
+type CommentPolicy int
+
+const (
+	// CommentKeep keeps the existing comment, ignoring the incoming one. This is the default.
+	CommentKeep CommentPolicy = iota
+
+	// CommentReplace replaces the existing comment with the incoming one, unless the
+	// incoming comment is empty.
+	CommentReplace
+
+	// CommentAppend appends the incoming comment to the existing one, separated by a blank
+	// line. An empty incoming comment, or one identical to the existing comment, is not appended.
+	CommentAppend
+)
+
+// MergeOption is a functional option for configuring [Archive.Merge].
+type MergeOption func(*mergeConfig) error
+
+// mergeConfig holds the configuration for a single call to [Archive.Merge].
+type mergeConfig struct {
+	resolve  func(name, existing, incoming string) (string, error) // Custom conflict resolution, if set
+	conflict ConflictPolicy                                        // What to do about conflicting files
+	comment  CommentPolicy                                         // How to combine the comments
+}
+
+// WithConflict is a [MergeOption] that sets what to do when both archives contain
+// a file with the same name but different contents, see [ConflictPolicy].
+func WithConflict(policy ConflictPolicy) MergeOption {
+	return func(cfg *mergeConfig) error {
+		if policy < ConflictOverride || policy > ConflictError {
+			return fmt.Errorf("WithConflict: invalid ConflictPolicy %d", policy)
+		}
+
+		cfg.conflict = policy
+
+		return nil
+	}
+}
+
+// WithResolver is a [MergeOption] that resolves conflicting files by calling resolve with
+// the file's name, its existing contents and the incoming contents. The file's contents
+// are set to whatever resolve returns, or if it returns an error, the merge is abandoned
+// and the error returned.
+//
+// WithResolver takes precedence over [WithConflict].
+func WithResolver(resolve func(name, existing, incoming string) (string, error)) MergeOption {
+	return func(cfg *mergeConfig) error {
+		if resolve == nil {
+			return errors.New("WithResolver: resolve function must not be nil")
+		}
+
+		cfg.resolve = resolve
+
+		return nil
+	}
+}
+
+// WithCommentMerge is a [MergeOption] that sets how the two archive comments are
+// combined, see [CommentPolicy].
+func WithCommentMerge(policy CommentPolicy) MergeOption {
+	return func(cfg *mergeConfig) error {
+		if policy < CommentKeep || policy > CommentAppend {
+			return fmt.Errorf("WithCommentMerge: invalid CommentPolicy %d", policy)
+		}
+
+		cfg.comment = policy
+
+		return nil
+	}
+}
+
+// Merge merges the files from src into the archive.
+//
+// Files only in src are added after the archive's existing files, in the order they
+// appear in src. Files in both with identical contents are left alone, and files in both
+// with different contents are handled according to the [ConflictPolicy] (override by
+// default) or a resolver passed with [WithResolver], keeping their existing position.
+// The comments are combined according to the [CommentPolicy] (keep by default).
+//
+// Incoming files are added as if by [Archive.Write], so are subject to the archive's
+// own whitespace handling, [NamePolicy] and [MarkerPolicy].
+//
+// To layer several archives, call Merge with each in turn. Merge either merges every
+// file or none of them, the archive is left untouched if an error is returned.
+func (a *Archive) Merge(src *Archive, options ...MergeOption) error {
+	if a == nil {
+		return errors.New("Merge called on a nil Archive")
+	}
+
+	cfg := mergeConfig{}
+
+	var errs error
+	for _, option := range options {
+		errs = errors.Join(errs, option(&cfg))
+	}
+
+	if errs != nil {
+		return errs
+	}
+
+	work := a.Clone()
+
+	if err := work.mergeComment(src.Comment(), cfg.comment); err != nil {
+		return fmt.Errorf("Merge: %w", err)
+	}
+
+	for name, incoming := range src.unique() {
+		contents := incoming
+
+		if existing, ok := work.Read(name); ok {
+			if existing == work.normalise(incoming) {
+				continue
+			}
+
+			switch {
+			case cfg.resolve != nil:
+				resolved, err := cfg.resolve(name, existing, incoming)
+				if err != nil {
+					return fmt.Errorf("Merge: %q: %w", name, err)
+				}
+
+				contents = resolved
+			case cfg.conflict == ConflictKeep:
+				continue
+			case cfg.conflict == ConflictError:
+				errs = errors.Join(errs, fmt.Errorf("Merge: %q: %w", name, ErrMergeConflict))
+				continue
+			}
+		}
+
+		if err := work.Write(name, contents); err != nil {
+			errs = errors.Join(errs, fmt.Errorf("Merge: %w", err))
+		}
+	}
+
+	if errs != nil {
+		return errs
+	}
+
+	*a = *work
+
+	return nil
+}
+
+// mergeComment combines the archive's comment with incoming according to policy.
+func (a *Archive) mergeComment(incoming string, policy CommentPolicy) error {
+	if incoming == "" || incoming == a.comment {
+		return nil
+	}
+
+	switch policy {
+	case CommentReplace:
+		return WithComment(incoming)(a)
+	case CommentAppend:
+		if a.comment == "" {
+			return WithComment(incoming)(a)
+		}
+
+		separator := "\n\n"
+		if a.verbatim {
+			// Verbatim comments already end in a newline
+			separator = "\n"
+		}
+
+		return WithComment(a.comment + separator + incoming)(a)
+	default:
+		return nil
+	}
+}
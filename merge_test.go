@@ -0,0 +1,137 @@
+package txtar_test
+
+import (
+	"errors"
+	"testing"
+
+	"go.followtheprocess.codes/test"
+	"go.followtheprocess.codes/txtar"
+)
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		wantErr error               // Expected error, if any
+		name    string              // Name of the test case
+		want    string              // Expected archive afterwards
+		options []txtar.MergeOption // Options to pass to Merge
+	}{
+		{
+			name: "default",
+			want: "Base comment\n\n-- shared.txt --\nshared\n-- config.yml --\noverride: true\n-- base.txt --\nbase only\n-- extra.txt --\nextra\n",
+		},
+		{
+			name:    "override",
+			options: []txtar.MergeOption{txtar.WithConflict(txtar.ConflictOverride)},
+			want:    "Base comment\n\n-- shared.txt --\nshared\n-- config.yml --\noverride: true\n-- base.txt --\nbase only\n-- extra.txt --\nextra\n",
+		},
+		{
+			name:    "keep",
+			options: []txtar.MergeOption{txtar.WithConflict(txtar.ConflictKeep)},
+			want:    "Base comment\n\n-- shared.txt --\nshared\n-- config.yml --\noverride: false\n-- base.txt --\nbase only\n-- extra.txt --\nextra\n",
+		},
+		{
+			name:    "error",
+			options: []txtar.MergeOption{txtar.WithConflict(txtar.ConflictError)},
+			wantErr: txtar.ErrMergeConflict,
+		},
+		{
+			name: "resolver",
+			options: []txtar.MergeOption{
+				txtar.WithConflict(txtar.ConflictError), // Resolver wins
+				txtar.WithResolver(func(name, existing, incoming string) (string, error) {
+					return name + ":\n" + existing + incoming, nil
+				}),
+			},
+			want: "Base comment\n\n-- shared.txt --\nshared\n-- config.yml --\nconfig.yml:\noverride: false\noverride: true\n-- base.txt --\nbase only\n-- extra.txt --\nextra\n",
+		},
+		{
+			name: "resolver error",
+			options: []txtar.MergeOption{
+				txtar.WithResolver(func(name, existing, incoming string) (string, error) {
+					return "", errors.ErrUnsupported
+				}),
+			},
+			wantErr: errors.ErrUnsupported,
+		},
+		{
+			name:    "replace comment",
+			options: []txtar.MergeOption{txtar.WithCommentMerge(txtar.CommentReplace)},
+			want:    "Override comment\n\n-- shared.txt --\nshared\n-- config.yml --\noverride: true\n-- base.txt --\nbase only\n-- extra.txt --\nextra\n",
+		},
+		{
+			name:    "append comment",
+			options: []txtar.MergeOption{txtar.WithCommentMerge(txtar.CommentAppend)},
+			want:    "Base comment\n\nOverride comment\n\n-- shared.txt --\nshared\n-- config.yml --\noverride: true\n-- base.txt --\nbase only\n-- extra.txt --\nextra\n",
+		},
+		{
+			name:    "bad conflict policy",
+			options: []txtar.MergeOption{txtar.WithConflict(txtar.ConflictPolicy(42))},
+			wantErr: errors.New("WithConflict: invalid ConflictPolicy 42"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base, err := txtar.New(
+				txtar.WithComment("Base comment"),
+				txtar.WithFile("shared.txt", "shared"),
+				txtar.WithFile("config.yml", "override: false"),
+				txtar.WithFile("base.txt", "base only"),
+			)
+			test.Ok(t, err)
+
+			override, err := txtar.New(
+				txtar.WithComment("Override comment"),
+				txtar.WithFile("extra.txt", "extra"),
+				txtar.WithFile("config.yml", "override: true"),
+				txtar.WithFile("shared.txt", "  shared\n\n"), // Same once normalised so not a conflict
+			)
+			test.Ok(t, err)
+
+			before := base.String()
+
+			err = base.Merge(override, tt.options...)
+			if tt.wantErr != nil {
+				test.Err(t, err)
+
+				if !errors.Is(err, tt.wantErr) {
+					test.Equal(t, err.Error(), tt.wantErr.Error())
+				}
+
+				// Failed merges must leave the archive alone
+				test.Diff(t, base.String(), before)
+
+				return
+			}
+
+			test.Ok(t, err)
+			test.Diff(t, base.String(), tt.want)
+		})
+	}
+}
+
+func TestMergeLayers(t *testing.T) {
+	archive, err := txtar.New(txtar.WithFile("a.txt", "base"))
+	test.Ok(t, err)
+
+	layers := []string{"one", "two", "three"}
+	for _, layer := range layers {
+		src, err := txtar.New(
+			txtar.WithComment(layer),
+			txtar.WithFile("a.txt", layer),
+			txtar.WithFile(layer+".txt", layer),
+		)
+		test.Ok(t, err)
+		test.Ok(t, archive.Merge(src, txtar.WithCommentMerge(txtar.CommentAppend)))
+	}
+
+	want := "one\n\ntwo\n\nthree\n\n-- a.txt --\nthree\n-- one.txt --\none\n-- two.txt --\ntwo\n-- three.txt --\nthree\n"
+	test.Diff(t, archive.String(), want)
+
+	// Merging nothing changes nothing
+	test.Ok(t, archive.Merge(nil))
+	test.Diff(t, archive.String(), want)
+
+	var missing *txtar.Archive
+	test.Err(t, missing.Merge(archive))
+}
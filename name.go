@@ -0,0 +1,209 @@
+package txtar
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Sentinel errors describing why a file name is invalid, these are available as
+// the cause of a [NameError] and so can be checked with [errors.Is].
+var (
+	// ErrEmptyName is the cause of a [NameError] for a name that is empty or refers
+	// to the root of the archive itself (e.g. "." or "a/..").
+	ErrEmptyName = errors.New("file name is empty")
+
+	// ErrAbsoluteName is the cause of a [NameError] for an absolute path, such as
+	// "/etc/passwd" or "C:/Windows".
+	ErrAbsoluteName = errors.New("file name is an absolute path")
+
+	// ErrEscapingName is the cause of a [NameError] for a path that escapes the root
+	// of the archive, such as "../x".
+	ErrEscapingName = errors.New("file name escapes the archive root")
+
+	// ErrUnmarkableName is the cause of a [NameError] for a name that cannot be written
+	// as a file marker and parsed back again, e.g. because it contains a newline or " --".
+	ErrUnmarkableName = errors.New("file name cannot be written as a file marker")
+
+	// ErrUncleanName is the cause of a [NameError] under [NameStrict] for an otherwise
+	// valid name that is not in the normalised form [ValidName] would produce.
+	ErrUncleanName = errors.New("file name is not in normalised form")
+)
+
+// NameError is the error returned when a file name fails validation, see [ValidName].
+type NameError struct {
+	Err  error  // The underlying cause, one of the package sentinel errors
+	Name string // The offending name, as given
+}
+
+// Error implements the error interface for a [NameError].
+func (e *NameError) Error() string {
+	return fmt.Sprintf("invalid file name %q: %v", e.Name, e.Err)
+}
+
+// Unwrap returns the underlying cause of the [NameError].
+func (e *NameError) Unwrap() error {
+	return e.Err
+}
+
+// NamePolicy determines how an [Archive] validates the names of files added to it,
+// either through [Archive.Write] or when parsed.
+type NamePolicy int
+
+const (
+	// NameUnchecked accepts any name, only trimming leading and trailing whitespace. This
+	// is the default, and matches the original [golang.org/x/tools/txtar] package.
+	//
+	// [golang.org/x/tools/txtar]: https://pkg.go.dev/golang.org/x/tools/txtar
+	NameUnchecked NamePolicy = iota
+
+	// NameClean normalises every name with [ValidName], returning a [*NameError] for
+	// any that are invalid.
+	//
+	// Names passed to [Archive.Read], [Archive.Has] and [Archive.Delete] are normalised
+	// in the same way, so "a\b" finds the file stored as "a/b".
+	NameClean
+
+	// NameStrict returns a [*NameError] for any name that is invalid or is not already
+	// in the normalised form [ValidName] would produce.
+	NameStrict
+)
+
+// ValidName validates and normalises a file name for use in an archive.
+//
+// Leading and trailing whitespace is trimmed, backslashes are converted to forward
+// slashes and the result is cleaned with [path.Clean], so "a\\b//c/./d" becomes "a/b/c/d".
+//
+// ValidName returns a [*NameError] if the name is empty, is absolute, escapes the root
+// of the archive or cannot be written as a file marker and parsed back unchanged.
+func ValidName(name string) (string, error) {
+	clean := strings.TrimSpace(name)
+
+	// The format itself copes with " --" inside a name (only the last one ends the marker)
+	// but other tools and human readers may not, so be conservative
+	if strings.ContainsAny(clean, "\r\n") || strings.Contains(clean, string(markerEnd)) {
+		return "", &NameError{Name: name, Err: ErrUnmarkableName}
+	}
+
+	clean, err := localName(clean)
+	if err != nil {
+		return "", &NameError{Name: name, Err: err}
+	}
+
+	if got, _ := isMarker([]byte("-- " + clean + " --")); got != clean {
+		return "", &NameError{Name: name, Err: ErrUnmarkableName}
+	}
+
+	return clean, nil
+}
+
+// localName converts backslashes in name to forward slashes and cleans it with
+// [path.Clean], returning [ErrEmptyName], [ErrAbsoluteName] or [ErrEscapingName] if
+// the result does not refer to something beneath the root.
+//
+// It is the part of [ValidName] concerned only with paths, for use where the name
+// need not survive a file marker.
+func localName(name string) (string, error) {
+	clean := path.Clean(strings.ReplaceAll(name, `\`, "/"))
+
+	switch {
+	case clean == ".":
+		return "", ErrEmptyName
+	case path.IsAbs(clean) || hasVolume(clean):
+		return "", ErrAbsoluteName
+	case clean == ".." || strings.HasPrefix(clean, "../"):
+		return "", ErrEscapingName
+	}
+
+	return clean, nil
+}
+
+// WithNamePolicy is an [Option] that sets how an [Archive] validates the names of
+// files added to it, see [NamePolicy].
+//
+// WithNamePolicy only affects files added after it, so should be passed to [New]
+// before any [WithFile].
+func WithNamePolicy(policy NamePolicy) Option {
+	return func(a *Archive) error {
+		if err := validNamePolicy(policy); err != nil {
+			return fmt.Errorf("WithNamePolicy: %w", err)
+		}
+
+		a.names = policy
+
+		return nil
+	}
+}
+
+// CheckNames is a [ParseOption] that validates the names of parsed files according to
+// policy, a name failing validation causes a [*ParseError] wrapping a [*NameError].
+//
+// The resulting [Archive] also has this [NamePolicy], as if created with [WithNamePolicy].
+func CheckNames(policy NamePolicy) ParseOption {
+	return func(cfg *parseConfig) error {
+		if err := validNamePolicy(policy); err != nil {
+			return fmt.Errorf("CheckNames: %w", err)
+		}
+
+		cfg.names = policy
+
+		return nil
+	}
+}
+
+// validNamePolicy returns an error if policy is not a known [NamePolicy].
+func validNamePolicy(policy NamePolicy) error {
+	if policy < NameUnchecked || policy > NameStrict {
+		return fmt.Errorf("invalid NamePolicy %d", policy)
+	}
+
+	return nil
+}
+
+// checkName applies the archive's [NamePolicy] to a name being added to it,
+// returning the name to store it under.
+func (a *Archive) checkName(name string) (string, error) {
+	switch a.names {
+	case NameClean:
+		return ValidName(name)
+	case NameStrict:
+		clean, err := ValidName(name)
+		if err != nil {
+			return "", err
+		}
+
+		if clean != strings.TrimSpace(name) {
+			return "", &NameError{Name: name, Err: ErrUncleanName}
+		}
+
+		return clean, nil
+	default:
+		return strings.TrimSpace(name), nil
+	}
+}
+
+// key returns the name a file would be stored under, for looking it up in the archive.
+//
+// Invalid names cannot be in the archive under [NameClean] so they are returned
+// as is (trimmed), and will simply not be found.
+func (a *Archive) key(name string) string {
+	if a.names == NameClean {
+		if clean, err := ValidName(name); err == nil {
+			return clean
+		}
+	}
+
+	return strings.TrimSpace(name)
+}
+
+// hasVolume reports whether name starts with a Windows drive letter e.g. "C:".
+func hasVolume(name string) bool {
+	if len(name) < 2 || name[1] != ':' {
+		return false
+	}
+
+	c := name[0]
+
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
@@ -0,0 +1,149 @@
+package txtar_test
+
+import (
+	"strings"
+	"testing"
+
+	"go.followtheprocess.codes/test"
+	"go.followtheprocess.codes/txtar"
+)
+
+func TestValidName(t *testing.T) {
+	tests := []struct {
+		cause error  // Expected sentinel cause, nil if the name is valid
+		name  string // Name to validate
+		want  string // Expected normalised name
+	}{
+		{name: "file.txt", want: "file.txt"},
+		{name: "  dir/file.txt\t", want: "dir/file.txt"},
+		{name: `a\b`, want: "a/b"},
+		{name: "a//b", want: "a/b"},
+		{name: "./a/./b/../c", want: "a/c"},
+		{name: "a/", want: "a"},
+		{name: "not-a-marker--", want: "not-a-marker--"},
+		{name: "", cause: txtar.ErrEmptyName},
+		{name: "   ", cause: txtar.ErrEmptyName},
+		{name: ".", cause: txtar.ErrEmptyName},
+		{name: "a/..", cause: txtar.ErrEmptyName},
+		{name: "/etc/passwd", cause: txtar.ErrAbsoluteName},
+		{name: `\windows`, cause: txtar.ErrAbsoluteName},
+		{name: `C:\Windows`, cause: txtar.ErrAbsoluteName},
+		{name: "c:file", cause: txtar.ErrAbsoluteName},
+		{name: "..", cause: txtar.ErrEscapingName},
+		{name: "../x", cause: txtar.ErrEscapingName},
+		{name: `a\..\..\x`, cause: txtar.ErrEscapingName},
+		{name: "line\nbreak", cause: txtar.ErrUnmarkableName},
+		{name: "carriage\rreturn", cause: txtar.ErrUnmarkableName},
+		{name: "ends --", cause: txtar.ErrUnmarkableName},
+		{name: "weird -- name", cause: txtar.ErrUnmarkableName},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := txtar.ValidName(tt.name)
+			if tt.cause == nil {
+				test.Ok(t, err)
+				test.Equal(t, got, tt.want)
+
+				return
+			}
+
+			test.ErrorIs(t, err, tt.cause)
+			nameErr := test.ErrorAs[*txtar.NameError](t, err)
+			test.Equal(t, nameErr.Name, tt.name)
+			test.Equal(t, got, "")
+		})
+	}
+}
+
+func TestNamePolicy(t *testing.T) {
+	t.Run("unchecked", func(t *testing.T) {
+		archive, err := txtar.New()
+		test.Ok(t, err)
+
+		test.Ok(t, archive.Write("../x", "stuff"))
+		test.True(t, archive.Has("../x"))
+	})
+
+	t.Run("clean", func(t *testing.T) {
+		archive, err := txtar.New(txtar.WithNamePolicy(txtar.NameClean))
+		test.Ok(t, err)
+
+		test.Ok(t, archive.Write(`dir\file.txt`, "stuff"))
+		test.True(t, archive.Has("dir/file.txt"))
+		test.True(t, archive.Has(`dir\\file.txt`), test.Context("Lookups should be normalised too"))
+
+		test.Ok(t, archive.Write("dir//file.txt", "new stuff"))
+		test.Equal(t, archive.Size(), 1)
+
+		contents, ok := archive.Read("./dir/file.txt")
+		test.True(t, ok)
+		test.Equal(t, contents, "new stuff\n")
+
+		err = archive.Write("/etc/passwd", "nope")
+		test.ErrorIs(t, err, txtar.ErrAbsoluteName)
+		test.False(t, archive.Has("../x"))
+
+		archive.Delete(`dir\file.txt`)
+		test.Equal(t, archive.Size(), 0)
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		archive, err := txtar.New(txtar.WithNamePolicy(txtar.NameStrict))
+		test.Ok(t, err)
+
+		test.Ok(t, archive.Write(" dir/file.txt ", "stuff"))
+		test.ErrorIs(t, archive.Write(`dir\file.txt`, "stuff"), txtar.ErrUncleanName)
+		test.ErrorIs(t, archive.Write("../x", "stuff"), txtar.ErrEscapingName)
+		test.Equal(t, archive.Size(), 1)
+	})
+
+	t.Run("option order", func(t *testing.T) {
+		_, err := txtar.New(
+			txtar.WithNamePolicy(txtar.NameClean),
+			txtar.WithFile("fine.txt", "fine"),
+			txtar.WithFile("../x", "stuff"),
+		)
+		test.ErrorIs(t, err, txtar.ErrEscapingName)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := txtar.New(txtar.WithNamePolicy(42))
+		test.Err(t, err)
+
+		_, err = txtar.ParseWith(strings.NewReader("-- a --\n"), txtar.CheckNames(-1))
+		test.Err(t, err)
+	})
+}
+
+func TestParseCheckNames(t *testing.T) {
+	input := "comment\n-- dir\\file.txt --\nstuff\n-- ../escape.txt --\nbad\n"
+
+	// By default anything goes
+	archive, err := txtar.Parse(strings.NewReader(input))
+	test.Ok(t, err)
+	test.True(t, archive.Has("../escape.txt"))
+
+	_, err = txtar.ParseWith(strings.NewReader(input), txtar.CheckNames(txtar.NameClean))
+	test.ErrorIs(t, err, txtar.ErrEscapingName)
+	test.ErrorAs[*txtar.NameError](t, err)
+
+	parseErr := test.ErrorAs[*txtar.ParseError](t, err)
+	test.Equal(t, parseErr.Line, 4)
+	test.Equal(t, parseErr.Marker, "-- ../escape.txt --")
+
+	// Cleaned names take part in duplicate detection
+	_, err = txtar.ParseWith(
+		strings.NewReader("-- a/b --\n-- a//b --\n"),
+		txtar.CheckNames(txtar.NameClean),
+		txtar.OnDuplicate(txtar.DuplicateError),
+	)
+	test.ErrorIs(t, err, txtar.ErrDuplicateFile)
+
+	archive, err = txtar.ParseWith(strings.NewReader("-- a\\b --\nstuff\n"), txtar.CheckNames(txtar.NameClean))
+	test.Ok(t, err)
+	test.Equal(t, archive.String(), "-- a/b --\nstuff\n")
+
+	// And the archive keeps the policy
+	test.ErrorIs(t, archive.Write("/abs", ""), txtar.ErrAbsoluteName)
+}
@@ -8,12 +8,46 @@ type Option func(*Archive) error
 // WithComment is an [Option] that sets the top level comment for an [Archive].
 //
 // Leading and trailing whitespace is stripped from the comment before adding so that
-// the formatting is consistent when printing an archive.
+// the formatting is consistent when printing an archive. If the archive is in verbatim
+// mode (see [WithVerbatim]) the comment is stored as is, with a final newline added if missing.
+//
+// Like [Archive.Write], a comment containing a line that would be parsed as a file
+// marker is rejected unless the archive's [MarkerPolicy] says otherwise.
 //
 // Successive calls overwrite any previous comment.
 func WithComment(comment string) Option {
 	return func(a *Archive) error {
-		a.comment = strings.TrimSpace(comment)
+		if a.verbatim {
+			comment = fixNL(comment)
+		} else {
+			comment = strings.TrimSpace(comment)
+		}
+
+		if err := a.checkMarkers("", comment); err != nil {
+			return err
+		}
+
+		a.comment = comment
+
+		return nil
+	}
+}
+
+// WithVerbatim is an [Option] that puts an [Archive] in verbatim mode.
+//
+// By default, leading and trailing whitespace is trimmed from the comment and the contents
+// of every file. In verbatim mode they are instead stored exactly as given, the only
+// modification being a final newline added if one is missing, as the txtar format requires.
+//
+// When serialising a verbatim archive, no blank line is inserted between the comment
+// and the first file, so an archive parsed with [Verbatim] serialises back to the
+// exact same text (other than "\r\n" line endings which are always normalised to "\n").
+//
+// WithVerbatim only affects comments and files added after it, so should be passed
+// to [New] before any [WithComment] or [WithFile].
+func WithVerbatim() Option {
+	return func(a *Archive) error {
+		a.verbatim = true
 
 		return nil
 	}
@@ -31,3 +65,56 @@ func WithFile(name, contents string) Option {
 		return a.Write(name, contents)
 	}
 }
+
+// ParseOption is a functional option for configuring how an [Archive] is parsed
+// from its serialised representation, see [ParseWith].
+type ParseOption func(*parseConfig) error
+
+// parseConfig holds the configuration for a single call to [ParseWith].
+type parseConfig struct {
+	lenient  bool // Accept anything golang.org/x/tools/txtar would accept
+	verbatim bool // Preserve whitespace in the comment and file contents
+	quoted   bool // Unquote lines quoted by MarkerQuote
+
+	names      NamePolicy      // How to validate file names
+	duplicates DuplicatePolicy // How to handle files with the same name
+}
+
+// Lenient is a [ParseOption] that relaxes the parser to accept every document
+// the original [golang.org/x/tools/txtar] package accepts.
+//
+// By default [Parse] is stricter than the original and will error on empty archives,
+// archives containing only a comment and archives with malformed file markers. With
+// Lenient, an empty document produces an empty [Archive] and a document with no
+// complete file markers is treated entirely as the archive comment.
+//
+// [golang.org/x/tools/txtar]: https://pkg.go.dev/golang.org/x/tools/txtar
+func Lenient() ParseOption {
+	return func(cfg *parseConfig) error {
+		cfg.lenient = true
+
+		return nil
+	}
+}
+
+// Verbatim is a [ParseOption] that parses the document into an [Archive] in verbatim
+// mode (see [WithVerbatim]), preserving all the whitespace in the comment and file contents.
+//
+// Combined with [Archive.String], this allows a document to be parsed, edited and written
+// back without disturbing the formatting of anything that wasn't changed.
+func Verbatim() ParseOption {
+	return func(cfg *parseConfig) error {
+		cfg.verbatim = true
+
+		return nil
+	}
+}
+
+// DumpOption is a functional option for configuring how an [Archive] is serialised
+// by [Dump] and [DumpFile].
+type DumpOption func(*dumpConfig) error
+
+// dumpConfig holds the configuration for a single call to [Dump].
+type dumpConfig struct {
+	sorted bool // Write files sorted by name
+}
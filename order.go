@@ -0,0 +1,151 @@
+package txtar
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io/fs"
+	"slices"
+)
+
+// WithSorted is an [Option] that causes the archive to always be serialised with its
+// files sorted by name, by [Archive.String], [Dump] and [DumpFile], regardless of the
+// order they were added in.
+//
+// This only affects the serialised output, [Archive.Files] still yields files in archive
+// order. Use [Archive.Sort] to sort the archive itself.
+func WithSorted() Option {
+	return func(a *Archive) error {
+		a.sorted = true
+
+		return nil
+	}
+}
+
+// Sorted is a [DumpOption] that writes the archive's files sorted by name, as if
+// the archive had been created with [WithSorted].
+func Sorted() DumpOption {
+	return func(cfg *dumpConfig) error {
+		cfg.sorted = true
+
+		return nil
+	}
+}
+
+// Sort sorts the files in the archive by name.
+//
+// The sort is stable so duplicate files (see [DuplicateKeepAll]) keep their relative order.
+func (a *Archive) Sort() {
+	a.SortFunc(cmp.Compare[string])
+}
+
+// SortFunc sorts the files in the archive by name using the comparison function cmp,
+// which should return a negative number when a < b, a positive number when a > b
+// and zero when a == b, as with [slices.SortFunc].
+//
+// The sort is stable so files comparing equal keep their relative order.
+func (a *Archive) SortFunc(cmp func(a, b string) int) {
+	if a == nil {
+		return
+	}
+
+	slices.SortStableFunc(a.files, func(x, y file) int { return cmp(x.name, y.name) })
+	a.reindex()
+}
+
+// MoveBefore moves the file name so that it comes immediately before the file target.
+//
+// If either file does not exist, MoveBefore returns an error wrapping [fs.ErrNotExist].
+func (a *Archive) MoveBefore(name, target string) error {
+	return a.move("MoveBefore", name, target, 0)
+}
+
+// MoveAfter moves the file name so that it comes immediately after the file target.
+//
+// If either file does not exist, MoveAfter returns an error wrapping [fs.ErrNotExist].
+func (a *Archive) MoveAfter(name, target string) error {
+	return a.move("MoveAfter", name, target, 1)
+}
+
+// InsertAt writes a named file with contents to the archive at position i, shifting
+// any files at or after i along by one. i must be between 0 and [Archive.Size] inclusive,
+// i.e. an InsertAt with i == Size() is equivalent to [Archive.Write] of a new file.
+//
+// If the file already exists, its contents are overwritten and it is moved to position i.
+// Otherwise, contents and name are treated exactly as in [Archive.Write].
+func (a *Archive) InsertAt(i int, name, contents string) error {
+	if a == nil {
+		return errors.New("InsertAt called on a nil Archive")
+	}
+
+	if i < 0 || i > len(a.files) {
+		return fmt.Errorf("InsertAt: index %d out of range for archive with %d files", i, len(a.files))
+	}
+
+	if err := a.Write(name, contents); err != nil {
+		return err
+	}
+
+	// Write has put it in the index (either appended, or existing) so we can now move it
+	from, _ := a.find(a.key(name))
+	a.moveTo(from, min(i, len(a.files)-1))
+
+	return nil
+}
+
+// move implements [Archive.MoveBefore] and [Archive.MoveAfter], offset is where name
+// should end up relative to target.
+func (a *Archive) move(op, name, target string, offset int) error {
+	if a == nil {
+		return fmt.Errorf("%s called on a nil Archive", op)
+	}
+
+	from, ok := a.find(a.key(name))
+	if !ok {
+		return fmt.Errorf("%s: %q: %w", op, name, fs.ErrNotExist)
+	}
+
+	to, ok := a.find(a.key(target))
+	if !ok {
+		return fmt.Errorf("%s: %q: %w", op, target, fs.ErrNotExist)
+	}
+
+	if from == to {
+		return nil
+	}
+
+	// Taking the file out first shifts everything after it back by one
+	to += offset
+	if from < to {
+		to--
+	}
+
+	a.moveTo(from, to)
+
+	return nil
+}
+
+// moveTo moves the file at position from to position to, shifting the files in between.
+func (a *Archive) moveTo(from, to int) {
+	if from == to {
+		return
+	}
+
+	moving := a.files[from]
+	a.files = slices.Insert(slices.Delete(a.files, from, from+1), to, moving)
+	a.reindex()
+}
+
+// ordered returns the archive's files in the order they should be serialised.
+func (a *Archive) ordered(sorted bool) []file {
+	if !sorted || slices.IsSortedFunc(a.files, compareNames) {
+		return a.files
+	}
+
+	return slices.SortedStableFunc(slices.Values(a.files), compareNames)
+}
+
+// compareNames compares files by name.
+func compareNames(a, b file) int {
+	return cmp.Compare(a.name, b.name)
+}
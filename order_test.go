@@ -0,0 +1,250 @@
+package txtar_test
+
+import (
+	"bytes"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"go.followtheprocess.codes/test"
+	"go.followtheprocess.codes/txtar"
+)
+
+func TestSort(t *testing.T) {
+	archive := renameArchive(t, "c.txt", "a.txt", "b/z.txt", "b.txt")
+
+	archive.Sort()
+	test.EqualFunc(t, names(archive), []string{"a.txt", "b.txt", "b/z.txt", "c.txt"}, slices.Equal)
+
+	// Longest first, ties keep their current order
+	archive.SortFunc(func(a, b string) int { return len(b) - len(a) })
+	test.EqualFunc(t, names(archive), []string{"b/z.txt", "a.txt", "b.txt", "c.txt"}, slices.Equal)
+
+	// Lookups still work after reordering
+	contents, ok := archive.Read("c.txt")
+	test.True(t, ok)
+	test.Equal(t, contents, "c.txt contents\n")
+
+	var nilArchive *txtar.Archive
+	nilArchive.Sort()
+}
+
+func TestMove(t *testing.T) {
+	tests := []struct {
+		wantErr error    // Expected error, if any
+		name    string   // Name of the test case
+		file    string   // File to move
+		target  string   // File to move relative to
+		want    []string // Expected order afterwards
+		after   bool     // Use MoveAfter rather than MoveBefore
+	}{
+		{
+			name:   "before forwards",
+			file:   "a",
+			target: "d",
+			want:   []string{"b", "c", "a", "d"},
+		},
+		{
+			name:   "before backwards",
+			file:   "d",
+			target: "b",
+			want:   []string{"a", "d", "b", "c"},
+		},
+		{
+			name:   "before first",
+			file:   "c",
+			target: "a",
+			want:   []string{"c", "a", "b", "d"},
+		},
+		{
+			name:   "after forwards",
+			file:   "a",
+			target: "c",
+			after:  true,
+			want:   []string{"b", "c", "a", "d"},
+		},
+		{
+			name:   "after last",
+			file:   "b",
+			target: "d",
+			after:  true,
+			want:   []string{"a", "c", "d", "b"},
+		},
+		{
+			name:   "after backwards",
+			file:   "d",
+			target: "a",
+			after:  true,
+			want:   []string{"a", "d", "b", "c"},
+		},
+		{
+			name:   "itself",
+			file:   "b",
+			target: "b",
+			want:   []string{"a", "b", "c", "d"},
+		},
+		{
+			name:   "already there",
+			file:   "a",
+			target: "b",
+			want:   []string{"a", "b", "c", "d"},
+		},
+		{
+			name:    "missing file",
+			file:    "missing",
+			target:  "a",
+			wantErr: fs.ErrNotExist,
+		},
+		{
+			name:    "missing target",
+			file:    "a",
+			target:  "missing",
+			after:   true,
+			wantErr: fs.ErrNotExist,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			archive := renameArchive(t, "a", "b", "c", "d")
+
+			var err error
+			if tt.after {
+				err = archive.MoveAfter(tt.file, tt.target)
+			} else {
+				err = archive.MoveBefore(tt.file, tt.target)
+			}
+
+			if tt.wantErr != nil {
+				test.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			test.Ok(t, err)
+			test.EqualFunc(t, names(archive), tt.want, slices.Equal)
+
+			for _, name := range tt.want {
+				contents, ok := archive.Read(name)
+				test.True(t, ok)
+				test.Equal(t, contents, name+" contents\n")
+			}
+		})
+	}
+}
+
+func TestInsertAt(t *testing.T) {
+	tests := []struct {
+		name     string   // Name of the test case
+		file     string   // Name of the file to insert
+		want     []string // Expected order afterwards
+		index    int      // Index to insert at
+		wantErr  bool     // Whether InsertAt should error
+		existing bool     // Whether file already exists
+	}{
+		{
+			name:  "start",
+			file:  "new",
+			index: 0,
+			want:  []string{"new", "a", "b", "c"},
+		},
+		{
+			name:  "middle",
+			file:  "new",
+			index: 2,
+			want:  []string{"a", "b", "new", "c"},
+		},
+		{
+			name:  "end",
+			file:  "new",
+			index: 3,
+			want:  []string{"a", "b", "c", "new"},
+		},
+		{
+			name:     "existing",
+			file:     "c",
+			index:    0,
+			existing: true,
+			want:     []string{"c", "a", "b"},
+		},
+		{
+			name:     "existing to end",
+			file:     "a",
+			index:    3,
+			existing: true,
+			want:     []string{"b", "c", "a"},
+		},
+		{
+			name:    "negative",
+			file:    "new",
+			index:   -1,
+			wantErr: true,
+		},
+		{
+			name:    "too big",
+			file:    "new",
+			index:   4,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			archive := renameArchive(t, "a", "b", "c")
+
+			err := archive.InsertAt(tt.index, tt.file, "inserted")
+			test.WantErr(t, err, tt.wantErr)
+
+			if tt.wantErr {
+				test.Equal(t, archive.Size(), 3)
+				return
+			}
+
+			test.EqualFunc(t, names(archive), tt.want, slices.Equal)
+
+			contents, ok := archive.Read(tt.file)
+			test.True(t, ok)
+			test.Equal(t, contents, "inserted\n")
+		})
+	}
+}
+
+func TestWithSorted(t *testing.T) {
+	archive, err := txtar.New(
+		txtar.WithSorted(),
+		txtar.WithComment("comment"),
+		txtar.WithFile("z.txt", "z"),
+		txtar.WithFile("a.txt", "a"),
+		txtar.WithFile("m/n.txt", "n"),
+	)
+	test.Ok(t, err)
+
+	want := "comment\n\n-- a.txt --\na\n-- m/n.txt --\nn\n-- z.txt --\nz\n"
+	test.Diff(t, archive.String(), want)
+
+	buf := &bytes.Buffer{}
+	test.Ok(t, txtar.Dump(buf, archive))
+	test.Diff(t, buf.String(), want)
+
+	// The archive itself is untouched
+	test.EqualFunc(t, names(archive), []string{"z.txt", "a.txt", "m/n.txt"}, slices.Equal)
+}
+
+func TestDumpSorted(t *testing.T) {
+	archive := renameArchive(t, "b", "a")
+
+	buf := &bytes.Buffer{}
+	test.Ok(t, txtar.Dump(buf, archive, txtar.Sorted()))
+	test.Diff(t, buf.String(), "-- a --\na contents\n-- b --\nb contents\n")
+
+	// Without the option, it's archive order
+	test.Diff(t, archive.String(), "-- b --\nb contents\n-- a --\na contents\n")
+
+	path := filepath.Join(t.TempDir(), "sorted.txtar")
+	test.Ok(t, txtar.DumpFile(path, archive, txtar.Sorted()))
+
+	parsed, err := txtar.ParseFile(path)
+	test.Ok(t, err)
+	test.Equal(t, strings.Join(names(parsed), ","), "a,b")
+}
@@ -0,0 +1,622 @@
+package txtar
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Sentinel errors describing why a patch could not be applied, these are available
+// as the cause of a [PatchError] and so can be checked with [errors.Is].
+var (
+	// ErrMalformedPatch is the cause of a [PatchError] when a unified diff cannot be parsed.
+	ErrMalformedPatch = errors.New("malformed patch")
+
+	// ErrPatchConflict is the cause of a [PatchError] when a change does not apply to the
+	// archive, e.g. a hunk's context does not match or a file to be modified does not exist.
+	ErrPatchConflict = errors.New("patch does not apply")
+)
+
+// PatchError is the error returned from [Archive.Apply] and [Archive.ApplyPatch] when
+// a change cannot be applied.
+type PatchError struct {
+	Err  error  // The underlying cause, wrapping one of the package sentinel errors
+	Name string // Name of the file the change relates to, empty for the comment or the patch as a whole
+	Line int    // 1-based line number in the patch, 0 if not applying a patch or not relating to a specific line
+}
+
+// Error implements the error interface for a [PatchError].
+func (e *PatchError) Error() string {
+	s := &strings.Builder{}
+	s.WriteString("Patch: ")
+
+	if e.Name != "" {
+		s.WriteString(e.Name)
+		s.WriteString(": ")
+	}
+
+	if e.Line > 0 {
+		s.WriteString("line ")
+		s.WriteString(strconv.Itoa(e.Line))
+		s.WriteString(": ")
+	}
+
+	s.WriteString(e.Err.Error())
+
+	return s.String()
+}
+
+// Unwrap returns the underlying cause of the [PatchError].
+func (e *PatchError) Unwrap() error {
+	return e.Err
+}
+
+// Apply applies the changes described by d to the archive, as if turning the archive d
+// was created from (the first argument to [Diff]) into the second.
+//
+// Every change is checked against the archive: files to be modified, renamed or removed must
+// exist with exactly the contents in d, and files to be added or renamed to must not exist.
+// If any change fails, Apply returns a [*PatchError] wrapping [ErrPatchConflict] and leaves
+// the archive untouched.
+func (a *Archive) Apply(d *ArchiveDiff) error {
+	if a == nil {
+		return errors.New("Apply called on a nil Archive")
+	}
+
+	var changes []change
+
+	if d.CommentChanged() {
+		changes = append(changes, change{comment: true, apply: replace(d.OldComment, d.NewComment)})
+	}
+
+	for _, file := range slices.Concat(d.Removed, d.Renamed, d.Modified, d.Added) {
+		changes = append(changes, change{
+			oldName: file.OldName,
+			newName: file.NewName,
+			apply:   replace(file.Old, file.New),
+		})
+	}
+
+	return a.applyChanges(changes)
+}
+
+// ApplyPatch applies a patch in the unified diff format to the archive, such as that
+// produced by git diff against an extracted copy of the archive, or by [ArchiveDiff.String].
+//
+// Files are created, deleted, renamed and copied as described by git's extended headers
+// ("new file mode", "rename from" etc.) or a /dev/null file name. Leading "a/" and "b/"
+// prefixes are stripped from file names. Outside of git style patches (i.e. without a
+// "diff --git" line), a pseudo file named "comment" applies to the archive comment,
+// as written by [ArchiveDiff.String].
+//
+// Each hunk's context and removed lines must match the file exactly. As with patch(1),
+// a hunk that does not match at the line it names is tried at nearby lines before it
+// is considered not to apply. Binary patches are not supported.
+//
+// The patch is applied all or nothing: if it is malformed ([ErrMalformedPatch]) or
+// any part of it does not apply ([ErrPatchConflict]), ApplyPatch returns a [*PatchError]
+// and leaves the archive untouched.
+func (a *Archive) ApplyPatch(r io.Reader) error {
+	if a == nil {
+		return errors.New("ApplyPatch called on a nil Archive")
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	changes, err := parsePatch(string(data))
+	if err != nil {
+		return err
+	}
+
+	return a.applyChanges(changes)
+}
+
+// change is a single change to an archive, as described by either an [ArchiveDiff]
+// or a file in a unified diff.
+type change struct {
+	apply   func(contents string) (string, error) // Turn the old contents into the new
+	oldName string                                // Name of the file to change, empty if it is being created
+	newName string                                // Name after the change, empty if it is being deleted
+	line    int                                   // Line in the patch the change starts at, if there is one
+	copy    bool                                  // Whether oldName is being copied, rather than renamed
+	comment bool                                  // Whether this is a change to the comment rather than a file
+}
+
+// applyChanges applies each change in turn to a copy of the archive, only updating
+// the archive itself if they all succeed.
+func (a *Archive) applyChanges(changes []change) error {
+	work := a.Clone()
+
+	for _, c := range changes {
+		if err := work.applyChange(c); err != nil {
+			return err
+		}
+	}
+
+	*a = *work
+
+	return nil
+}
+
+// applyChange applies a single change to the archive.
+func (a *Archive) applyChange(c change) error {
+	name := c.oldName
+	if name == "" {
+		name = c.newName
+	}
+
+	fail := func(err error) error {
+		return &PatchError{Name: name, Line: c.line, Err: err}
+	}
+
+	conflict := func(format string, args ...any) error {
+		return fail(fmt.Errorf("%w: "+format, append([]any{ErrPatchConflict}, args...)...))
+	}
+
+	if c.comment {
+		after, err := c.apply(a.comment)
+		if err != nil {
+			return fail(err)
+		}
+
+		if err := WithComment(after)(a); err != nil {
+			return fail(err)
+		}
+
+		return nil
+	}
+
+	if c.oldName == "" {
+		if a.Has(c.newName) {
+			return conflict("file to be created already exists")
+		}
+
+		after, err := c.apply("")
+		if err != nil {
+			return fail(err)
+		}
+
+		if err := a.Write(c.newName, after); err != nil {
+			return fail(err)
+		}
+
+		return nil
+	}
+
+	before, ok := a.Read(c.oldName)
+	if !ok {
+		return conflict("file does not exist")
+	}
+
+	after, err := c.apply(before)
+	if err != nil {
+		return fail(err)
+	}
+
+	switch {
+	case c.newName == "":
+		if after != "" {
+			return conflict("file to be deleted has contents not removed by the patch")
+		}
+
+		a.Delete(c.oldName)
+
+		return nil
+	case c.copy:
+		if a.Has(c.newName) {
+			return conflict("copy destination %q already exists", c.newName)
+		}
+	case a.key(c.newName) != a.key(c.oldName):
+		if a.Has(c.newName) {
+			return conflict("rename destination %q already exists", c.newName)
+		}
+
+		if err := a.Rename(c.oldName, c.newName); err != nil {
+			return fail(err)
+		}
+	}
+
+	if err := a.Write(c.newName, after); err != nil {
+		return fail(err)
+	}
+
+	return nil
+}
+
+// replace returns a change function that expects contents of before and replaces
+// them with after, for applying an [ArchiveDiff].
+func replace(before, after string) func(string) (string, error) {
+	return func(contents string) (string, error) {
+		if contents != before {
+			return "", fmt.Errorf("%w: contents do not match", ErrPatchConflict)
+		}
+
+		return after, nil
+	}
+}
+
+// hunk is a single hunk from a unified diff.
+type hunk struct {
+	lines    []string // Lines of the hunk including their ' ', '-' or '+' prefix and newline (if any)
+	oldStart int      // Line number the hunk starts at in the old file
+	oldCount int      // Number of lines in the old file covered by the hunk
+	line     int      // Line number in the patch of the "@@" header
+}
+
+// apply returns the lines resulting from applying h to lines, starting the search for
+// a match no earlier than position from (for the preceding hunks) and offset from where
+// the hunk says it should be by the amount the previous hunk was out.
+//
+// It returns the position in lines just after the hunk and the new offset.
+func (h hunk) apply(out, lines []string, from, offset int) (result []string, next, newOffset int, err error) {
+	var old, replacement []string
+
+	for _, line := range h.lines {
+		switch line[0] {
+		case ' ':
+			old = append(old, line[1:])
+			replacement = append(replacement, line[1:])
+		case '-':
+			old = append(old, line[1:])
+		case '+':
+			replacement = append(replacement, line[1:])
+		}
+	}
+
+	// With no old lines, the start is the line the hunk comes after rather than the first
+	// line it covers
+	want := h.oldStart - 1
+	if h.oldCount == 0 {
+		want = h.oldStart
+	}
+
+	want += offset
+
+	// Search outwards from where we're told the hunk should be
+	last := len(lines) - len(old)
+	for delta := 0; want-delta >= from || want+delta <= last; delta++ {
+		for _, at := range []int{want - delta, want + delta} {
+			if at < from || at > last || !slices.Equal(lines[at:at+len(old)], old) {
+				continue
+			}
+
+			out = append(out, lines[from:at]...)
+			out = append(out, replacement...)
+
+			return out, at + len(old), at - (want - offset), nil
+		}
+	}
+
+	return nil, 0, 0, fmt.Errorf("%w: hunk at line %d does not match", ErrPatchConflict, h.line)
+}
+
+// applyHunks returns a function applying hunks in turn to some contents.
+func applyHunks(hunks []hunk) func(string) (string, error) {
+	return func(contents string) (string, error) {
+		lines := slices.Collect(strings.Lines(contents))
+
+		var (
+			out    []string
+			next   int
+			offset int
+			err    error
+		)
+
+		for _, h := range hunks {
+			out, next, offset, err = h.apply(out, lines, next, offset)
+			if err != nil {
+				return "", err
+			}
+		}
+
+		out = append(out, lines[next:]...)
+
+		return strings.Join(out, ""), nil
+	}
+}
+
+// parsePatch parses a unified diff into the changes it describes.
+func parsePatch(data string) ([]change, error) {
+	lines := slices.Collect(strings.Lines(data))
+
+	var (
+		changes []change
+		current *patchFile
+	)
+
+	finish := func() {
+		if current != nil {
+			changes = append(changes, current.change())
+			current = nil
+		}
+	}
+
+	malformed := func(line int, format string, args ...any) error {
+		err := fmt.Errorf("%w: "+format, append([]any{ErrMalformedPatch}, args...)...)
+		return &PatchError{Line: line, Err: err}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		text := strings.TrimRight(lines[i], "\r\n")
+		number := i + 1
+
+		switch {
+		case strings.HasPrefix(text, "diff --git "):
+			finish()
+
+			current = &patchFile{line: number, git: true}
+			current.oldName, current.newName = splitGitNames(strings.TrimPrefix(text, "diff --git "))
+
+		case strings.HasPrefix(text, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ "):
+			// A plain diff (not git) has nothing before the file names to start a new file
+			if current == nil || current.sawNames || len(current.hunks) != 0 {
+				finish()
+
+				current = &patchFile{line: number}
+			}
+
+			current.sawNames = true
+			current.oldName = patchName(strings.TrimPrefix(text, "--- "))
+			current.newName = patchName(strings.TrimPrefix(strings.TrimRight(lines[i+1], "\r\n"), "+++ "))
+			current.created = current.created || current.oldName == ""
+			current.deleted = current.deleted || current.newName == ""
+			i++
+
+		case strings.HasPrefix(text, "@@ "):
+			if current == nil {
+				return nil, malformed(number, "hunk outside of a file")
+			}
+
+			h, consumed, err := parseHunk(lines[i:], number)
+			if err != nil {
+				return nil, err
+			}
+
+			current.hunks = append(current.hunks, h)
+			i += consumed - 1
+
+		case current == nil:
+			// Preamble e.g. a commit message, ignored as patch(1) does
+
+		case strings.HasPrefix(text, "new file mode "):
+			current.created = true
+		case strings.HasPrefix(text, "deleted file mode "):
+			current.deleted = true
+		case strings.HasPrefix(text, "rename from "):
+			current.oldName = unquoteName(strings.TrimPrefix(text, "rename from "))
+		case strings.HasPrefix(text, "rename to "):
+			current.newName = unquoteName(strings.TrimPrefix(text, "rename to "))
+		case strings.HasPrefix(text, "copy from "):
+			current.copy = true
+			current.oldName = unquoteName(strings.TrimPrefix(text, "copy from "))
+		case strings.HasPrefix(text, "copy to "):
+			current.newName = unquoteName(strings.TrimPrefix(text, "copy to "))
+		case strings.HasPrefix(text, "GIT binary patch"), strings.HasPrefix(text, "Binary files "):
+			return nil, malformed(number, "binary patches are not supported")
+		}
+	}
+
+	finish()
+
+	if len(changes) == 0 {
+		return nil, malformed(0, "no changes found")
+	}
+
+	for _, c := range changes {
+		if c.oldName == "" && c.newName == "" && !c.comment {
+			return nil, malformed(c.line, "no file name")
+		}
+	}
+
+	return changes, nil
+}
+
+// patchFile accumulates the details of a single file while parsing a unified diff.
+type patchFile struct {
+	oldName  string // Old file name, empty for /dev/null
+	newName  string // New file name, empty for /dev/null
+	hunks    []hunk // The file's hunks
+	line     int    // Line the file starts on
+	git      bool   // Whether this is a git style diff i.e. starts with "diff --git"
+	sawNames bool   // Whether the ---/+++ lines have been seen
+	created  bool   // Whether the file is being created
+	deleted  bool   // Whether the file is being deleted
+	copy     bool   // Whether the file is being copied
+}
+
+// change returns the change described by the file.
+func (p *patchFile) change() change {
+	c := change{
+		oldName: p.oldName,
+		newName: p.newName,
+		line:    p.line,
+		copy:    p.copy,
+		comment: !p.git && p.oldName == commentName && p.newName == commentName,
+		apply:   applyHunks(p.hunks),
+	}
+
+	if p.created {
+		c.oldName = ""
+	}
+
+	if p.deleted {
+		c.newName = ""
+	}
+
+	return c
+}
+
+// commentName is the name of the pseudo file used for the comment in a unified diff.
+const commentName = "comment"
+
+// parseHunk parses the hunk starting at lines[0] (the "@@" header, which is on
+// line number of the patch), returning it and the number of lines consumed.
+func parseHunk(lines []string, number int) (hunk, int, error) {
+	malformed := func(line int, format string, args ...any) error {
+		err := fmt.Errorf("%w: "+format, append([]any{ErrMalformedPatch}, args...)...)
+		return &PatchError{Line: line, Err: err}
+	}
+
+	header := strings.TrimRight(lines[0], "\r\n")
+
+	var oldRange, newRange string
+	if _, err := fmt.Sscanf(header, "@@ %s %s @@", &oldRange, &newRange); err != nil ||
+		!strings.HasPrefix(oldRange, "-") || !strings.HasPrefix(newRange, "+") {
+		return hunk{}, 0, malformed(number, "invalid hunk header %q", header)
+	}
+
+	oldStart, oldCount, okOld := parseRange(oldRange[1:])
+	_, newCount, okNew := parseRange(newRange[1:])
+
+	if !okOld || !okNew {
+		return hunk{}, 0, malformed(number, "invalid hunk header %q", header)
+	}
+
+	h := hunk{oldStart: oldStart, oldCount: oldCount, line: number}
+
+	i := 1
+	for oldCount > 0 || newCount > 0 {
+		if i >= len(lines) {
+			return hunk{}, 0, malformed(number, "hunk is missing lines")
+		}
+
+		line := lines[i]
+
+		// Some tools strip the trailing space from blank context lines
+		if line == "\n" || line == "\r\n" {
+			line = " " + line
+		}
+
+		switch line[0] {
+		case ' ':
+			oldCount--
+			newCount--
+		case '-':
+			oldCount--
+		case '+':
+			newCount--
+		case '\\':
+			h.noNewline()
+			i++
+
+			continue
+		default:
+			return hunk{}, 0, malformed(number+i, "unexpected line in hunk %q", strings.TrimRight(line, "\r\n"))
+		}
+
+		if oldCount < 0 || newCount < 0 {
+			return hunk{}, 0, malformed(number, "hunk has more lines than its header says")
+		}
+
+		// CRLF is normalised as it is when parsing an archive
+		if strings.HasSuffix(line, "\r\n") {
+			line = strings.TrimSuffix(line, "\r\n") + "\n"
+		}
+
+		h.lines = append(h.lines, line)
+		i++
+	}
+
+	// A "\ No newline at end of file" may follow the final line
+	if i < len(lines) && strings.HasPrefix(lines[i], `\`) {
+		h.noNewline()
+		i++
+	}
+
+	return h, i, nil
+}
+
+// noNewline handles a "\ No newline at end of file" line, which applies to the previous line.
+func (h *hunk) noNewline() {
+	if len(h.lines) != 0 {
+		last := len(h.lines) - 1
+		h.lines[last] = strings.TrimSuffix(h.lines[last], "\n")
+	}
+}
+
+// parseRange parses a hunk range of the form "start,count" or "start", where the count
+// defaults to 1.
+func parseRange(s string) (start, count int, ok bool) {
+	first, second, hasCount := strings.Cut(s, ",")
+
+	start, err := strconv.Atoi(first)
+	if err != nil || start < 0 {
+		return 0, 0, false
+	}
+
+	count = 1
+	if hasCount {
+		count, err = strconv.Atoi(second)
+		if err != nil || count < 0 {
+			return 0, 0, false
+		}
+	}
+
+	return start, count, true
+}
+
+// patchName returns the file name from a ---/+++ line of a unified diff, stripping any
+// timestamp, quoting and a/ or b/ prefix. /dev/null gives an empty name.
+func patchName(s string) string {
+	// diff -u puts a timestamp after a tab
+	if name, _, ok := strings.Cut(s, "\t"); ok {
+		s = name
+	}
+
+	s = unquoteName(strings.TrimSpace(s))
+	if s == "/dev/null" {
+		return ""
+	}
+
+	return stripPrefix(s)
+}
+
+// splitGitNames splits the "a/x b/y" names from a "diff --git" line. This is ambiguous
+// for names containing spaces, which is why the ---/+++ and rename lines take precedence
+// when present.
+func splitGitNames(s string) (oldName, newName string) {
+	// If the names are the same (the common case), the line is exactly symmetrical
+	if half := len(s) / 2; len(s)%2 == 1 && s[half] == ' ' {
+		if oldName, newName := stripPrefix(s[:half]), stripPrefix(s[half+1:]); oldName == newName {
+			return oldName, newName
+		}
+	}
+
+	if i := strings.LastIndex(s, " b/"); i >= 0 {
+		return stripPrefix(unquoteName(s[:i])), stripPrefix(unquoteName(s[i+1:]))
+	}
+
+	return "", ""
+}
+
+// stripPrefix removes the a/ or b/ prefix git puts on file names.
+func stripPrefix(name string) string {
+	if rest, ok := strings.CutPrefix(name, "a/"); ok {
+		return rest
+	}
+
+	if rest, ok := strings.CutPrefix(name, "b/"); ok {
+		return rest
+	}
+
+	return name
+}
+
+// unquoteName undoes git's C style quoting of names containing special characters.
+func unquoteName(name string) string {
+	if !strings.HasPrefix(name, `"`) {
+		return name
+	}
+
+	if unquoted, err := strconv.Unquote(name); err == nil {
+		return unquoted
+	}
+
+	return name
+}
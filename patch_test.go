@@ -0,0 +1,273 @@
+package txtar_test
+
+import (
+	"strings"
+	"testing"
+
+	"go.followtheprocess.codes/test"
+	"go.followtheprocess.codes/txtar"
+)
+
+func TestApplyPatch(t *testing.T) {
+	archive, err := txtar.Parse(strings.NewReader(`A comment
+
+-- main.go --
+package main
+
+import "fmt"
+
+func main() {
+	fmt.Println("hello")
+}
+-- go.mod --
+module example
+
+go 1.26
+-- old.txt --
+to be renamed
+-- gone.txt --
+bye
+-- copied.txt --
+copy me
+`))
+	test.Ok(t, err)
+
+	// As produced by git diff on an extracted copy, with some preamble
+	patch := `commit message that should be ignored
+
+diff --git a/main.go b/main.go
+index 1234567..89abcde 100644
+--- a/main.go
++++ b/main.go
+@@ -4,4 +4,4 @@ import "fmt"
+
+ func main() {
+-	fmt.Println("hello")
++	fmt.Println("goodbye")
+ }
+diff --git a/old.txt b/dir/new.txt
+similarity index 100%
+rename from old.txt
+rename to dir/new.txt
+diff --git a/gone.txt b/gone.txt
+deleted file mode 100644
+index 1234567..0000000
+--- a/gone.txt
++++ /dev/null
+@@ -1 +0,0 @@
+-bye
+diff --git a/copied.txt b/copy.txt
+similarity index 100%
+copy from copied.txt
+copy to copy.txt
+diff --git a/added.txt b/added.txt
+new file mode 100644
+index 0000000..1234567
+--- /dev/null
++++ b/added.txt
+@@ -0,0 +1,2 @@
++brand
++new
+\ No newline at end of file
+diff --git a/empty.txt b/empty.txt
+new file mode 100644
+index 0000000..e69de29
+`
+	test.Ok(t, archive.ApplyPatch(strings.NewReader(patch)))
+
+	want := `A comment
+
+-- main.go --
+package main
+
+import "fmt"
+
+func main() {
+	fmt.Println("goodbye")
+}
+-- go.mod --
+module example
+
+go 1.26
+-- dir/new.txt --
+to be renamed
+-- copied.txt --
+copy me
+-- copy.txt --
+copy me
+-- added.txt --
+brand
+new
+-- empty.txt --
+`
+	test.Diff(t, archive.String(), want)
+}
+
+func TestApplyPatchOffset(t *testing.T) {
+	archive, err := txtar.New(
+		txtar.WithFile("file.txt", "new first line\nanother\none\ntwo\nthree\nfour\nfive\n"),
+	)
+	test.Ok(t, err)
+
+	// The patch was made before the first two lines were added, plain diff -u style
+	patch := "--- file.txt\t2026-01-01 00:00:00\n+++ file.txt\t2026-01-02 00:00:00\n@@ -2,3 +2,3 @@\n two\n-three\n+THREE\n four\n"
+	test.Ok(t, archive.ApplyPatch(strings.NewReader(patch)))
+
+	contents, ok := archive.Read("file.txt")
+	test.True(t, ok)
+	test.Equal(t, contents, "new first line\nanother\none\ntwo\nTHREE\nfour\nfive\n")
+}
+
+func TestApplyPatchErrors(t *testing.T) {
+	tests := []struct {
+		cause error  // Expected sentinel cause
+		name  string // Name of the test case
+		patch string // The patch to apply
+		file  string // Expected Name in the PatchError
+		line  int    // Expected Line in the PatchError
+	}{
+		{
+			name:  "context mismatch",
+			patch: "--- a/a.txt\n+++ b/a.txt\n@@ -1,2 +1,2 @@\n one\n-not two\n+2\n--- a/b.txt\n+++ b/b.txt\n@@ -1 +1 @@\n-b\n+B\n",
+			cause: txtar.ErrPatchConflict,
+			file:  "a.txt",
+			line:  1,
+		},
+		{
+			name:  "second file fails",
+			patch: "--- a/b.txt\n+++ b/b.txt\n@@ -1 +1 @@\n-b\n+B\n--- a/a.txt\n+++ b/a.txt\n@@ -1 +1 @@\n-nope\n+B\n",
+			cause: txtar.ErrPatchConflict,
+			file:  "a.txt",
+			line:  6,
+		},
+		{
+			name:  "missing file",
+			patch: "--- a/missing.txt\n+++ b/missing.txt\n@@ -1 +1 @@\n-b\n+B\n",
+			cause: txtar.ErrPatchConflict,
+			file:  "missing.txt",
+			line:  1,
+		},
+		{
+			name:  "create existing",
+			patch: "--- /dev/null\n+++ b/a.txt\n@@ -0,0 +1 @@\n+a\n",
+			cause: txtar.ErrPatchConflict,
+			file:  "a.txt",
+			line:  1,
+		},
+		{
+			name:  "rename onto existing",
+			patch: "diff --git a/a.txt b/b.txt\nrename from a.txt\nrename to b.txt\n",
+			cause: txtar.ErrPatchConflict,
+			file:  "a.txt",
+			line:  1,
+		},
+		{
+			name:  "delete with leftovers",
+			patch: "diff --git a/a.txt b/a.txt\ndeleted file mode 100644\n--- a/a.txt\n+++ /dev/null\n@@ -1 +0,0 @@\n-one\n",
+			cause: txtar.ErrPatchConflict,
+			file:  "a.txt",
+			line:  1,
+		},
+		{
+			name:  "empty",
+			patch: "",
+			cause: txtar.ErrMalformedPatch,
+		},
+		{
+			name:  "bad hunk header",
+			patch: "--- a/a.txt\n+++ b/a.txt\n@@ -x +1 @@\n",
+			cause: txtar.ErrMalformedPatch,
+			line:  3,
+		},
+		{
+			name:  "short hunk",
+			patch: "--- a/a.txt\n+++ b/a.txt\n@@ -1,2 +1,2 @@\n one\n",
+			cause: txtar.ErrMalformedPatch,
+			line:  3,
+		},
+		{
+			name:  "junk in hunk",
+			patch: "--- a/a.txt\n+++ b/a.txt\n@@ -1,2 +1,2 @@\n one\n?two\n",
+			cause: txtar.ErrMalformedPatch,
+			line:  5,
+		},
+		{
+			name:  "hunk without file",
+			patch: "@@ -1 +1 @@\n-a\n+b\n",
+			cause: txtar.ErrMalformedPatch,
+			line:  1,
+		},
+		{
+			name:  "binary",
+			patch: "diff --git a/a.txt b/a.txt\nGIT binary patch\nliteral 0\n",
+			cause: txtar.ErrMalformedPatch,
+			line:  2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			archive, err := txtar.New(
+				txtar.WithFile("a.txt", "one\ntwo\n"),
+				txtar.WithFile("b.txt", "b"),
+			)
+			test.Ok(t, err)
+
+			before := archive.String()
+
+			err = archive.ApplyPatch(strings.NewReader(tt.patch))
+			test.ErrorIs(t, err, tt.cause)
+
+			patchErr := test.ErrorAs[*txtar.PatchError](t, err)
+			test.Equal(t, patchErr.Name, tt.file)
+			test.Equal(t, patchErr.Line, tt.line)
+
+			test.Diff(t, archive.String(), before)
+		})
+	}
+}
+
+func TestApplyDiffRoundTrip(t *testing.T) {
+	before, err := txtar.New(
+		txtar.WithComment("Old comment\nwith lines"),
+		txtar.WithFile("same.txt", "unchanged"),
+		txtar.WithFile("modified.txt", strings.Repeat("line\n", 20)+"old\n"+strings.Repeat("line\n", 20)),
+		txtar.WithFile("removed.txt", "going away"),
+		txtar.WithFile("old/name.txt", "moving house"),
+		txtar.WithFile("empty.txt", ""),
+	)
+	test.Ok(t, err)
+
+	after, err := txtar.New(
+		txtar.WithComment("New comment\nwith lines"),
+		txtar.WithFile("same.txt", "unchanged"),
+		txtar.WithFile("modified.txt", "first\n"+strings.Repeat("line\n", 20)+"new\n"+strings.Repeat("line\n", 20)),
+		txtar.WithFile("new/name.txt", "moving house"),
+		txtar.WithFile("added.txt", "brand new"),
+		txtar.WithFile("another_empty.txt", ""),
+	)
+	test.Ok(t, err)
+
+	d := txtar.Diff(before, after)
+
+	t.Run("structured", func(t *testing.T) {
+		archive := before.Clone()
+		test.Ok(t, archive.Apply(d))
+		test.True(t, txtar.Diff(archive, after).Equal(), test.Context("Diff after Apply:\n%s", txtar.Diff(archive, after)))
+	})
+
+	t.Run("patch", func(t *testing.T) {
+		archive := before.Clone()
+		test.Ok(t, archive.ApplyPatch(strings.NewReader(d.String())))
+		test.True(t, txtar.Diff(archive, after).Equal(), test.Context("Diff after ApplyPatch:\n%s", txtar.Diff(archive, after)))
+	})
+
+	t.Run("conflict", func(t *testing.T) {
+		// Applying it twice shouldn't work
+		archive := after.Clone()
+		err := archive.Apply(d)
+		test.ErrorIs(t, err, txtar.ErrPatchConflict)
+		test.ErrorAs[*txtar.PatchError](t, err)
+		test.True(t, txtar.Equal(archive, after), test.Context("Failed Apply modified the archive"))
+	})
+}
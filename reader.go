@@ -0,0 +1,205 @@
+package txtar
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+)
+
+// readerBufferSize is the size of the buffer used by a [Reader], it bounds the memory
+// used when scanning an archive except for lines that may be file markers, see [Reader.fill].
+const readerBufferSize = 64 * 1024
+
+// Header describes a single file entry in a txtar archive, see [Reader.Next].
+type Header struct {
+	Name string // Name of the file, with leading and trailing whitespace trimmed
+	Line int    // 1-based line number of the file's marker line
+}
+
+// Reader provides sequential, streaming access to the contents of a txtar archive.
+//
+// It works like [archive/tar.Reader], the archive is split into sections: before the
+// first call to [Reader.Next], reading from the Reader yields the archive's comment. Each
+// call to Next advances to the next file in the archive, after which the Reader yields
+// that file's contents until [io.EOF].
+//
+// Unlike [Parse], a Reader scans for file markers incrementally using a bounded buffer
+// so the archive never needs to be held in memory in its entirety. It follows the
+// original txtar semantics exactly and so has no notion of a malformed archive, the
+// comment and file contents are yielded verbatim except for "\r\n" line endings which
+// are normalised to "\n".
+//
+// The only exception to the bounded buffer is a line beginning with "-- " that doesn't
+// fit in it, which is held in memory in full so that it can be recognised as a file
+// marker regardless of its length, as the original package does.
+type Reader struct {
+	r           *bufio.Reader // The buffered underlying reader
+	err         error         // Sticky error from the underlying reader
+	next        *Header       // The header for the next file, found while reading the current section
+	pending     []byte        // Data from the current section read from r but not yet returned
+	line        int           // 1-based line number of the next line to be read from r
+	atLineStart bool          // Whether the next byte read from r is at the start of a line
+	done        bool          // Whether the current section has been read to completion
+}
+
+// NewReader returns a new [Reader] reading a txtar archive from r.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{
+		r:           bufio.NewReaderSize(r, readerBufferSize),
+		line:        1,
+		atLineStart: true,
+	}
+}
+
+// Next advances to the next file in the archive, discarding any unread data from
+// the current section.
+//
+// The returned [Header] describes the file, its contents may then be read from
+// the Reader itself. At the end of the archive, Next returns [io.EOF].
+func (r *Reader) Next() (*Header, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	// Skip whatever remains of the current section
+	for !r.done {
+		r.pending = nil
+		if err := r.fill(); err != nil {
+			r.err = err
+			return nil, err
+		}
+	}
+
+	r.pending = nil
+
+	if r.next == nil {
+		return nil, io.EOF
+	}
+
+	header := r.next
+	r.next = nil
+	r.done = false
+
+	return header, nil
+}
+
+// Read reads from the current section of the archive: the comment before the first
+// call to [Reader.Next], or the contents of the current file thereafter.
+//
+// It returns [io.EOF] when the end of the section is reached.
+func (r *Reader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		if len(r.pending) == 0 {
+			if r.done || r.err != nil {
+				break
+			}
+
+			if err := r.fill(); err != nil {
+				r.err = err
+				break
+			}
+
+			continue
+		}
+
+		copied := copy(p[n:], r.pending)
+		r.pending = r.pending[copied:]
+		n += copied
+	}
+
+	if n == 0 && len(p) != 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+
+		return 0, io.EOF
+	}
+
+	return n, nil
+}
+
+// fill reads the next chunk of the current section into r.pending, a chunk is at
+// most a single line. If the chunk turns out to be a file marker, the current section
+// is marked as done and the marker's header is stashed for the next call to Next.
+//
+// Long lines are normally returned in buffer sized pieces, but one that starts like a
+// file marker is read in its entirety so the whole line can be checked.
+//
+// fill must only be called once r.pending has been fully consumed as it invalidates it.
+func (r *Reader) fill() error {
+	startOfLine := r.atLineStart
+
+	chunk, err := r.r.ReadSlice('\n')
+
+	if startOfLine && errors.Is(err, bufio.ErrBufferFull) && bytes.HasPrefix(chunk, marker) {
+		chunk, err = r.readLine(chunk)
+	}
+
+	switch {
+	case err == nil:
+		r.atLineStart = true
+	case errors.Is(err, bufio.ErrBufferFull):
+		// Line is longer than our buffer so we only have part of it, if it
+		// ends in the middle of a "\r\n" put the '\r' back so we can normalise
+		// it on the next go round
+		r.atLineStart = false
+		if len(chunk) > 1 && chunk[len(chunk)-1] == '\r' {
+			if err := r.r.UnreadByte(); err != nil {
+				return err
+			}
+
+			chunk = chunk[:len(chunk)-1]
+		}
+	case errors.Is(err, io.EOF):
+		// Whatever we have is the final line and there is no more to come
+		r.done = true
+	default:
+		return err
+	}
+
+	// Stupid windows, note this modifies the bufio.Reader's buffer in place
+	// but only the part of it we've already consumed
+	if n := len(chunk); n >= 2 && chunk[n-2] == '\r' && chunk[n-1] == '\n' {
+		chunk[n-2] = '\n'
+		chunk = chunk[:n-1]
+	}
+
+	// A marker must occupy the entirety of a line so we can only have found
+	// one if we read a whole line
+	if startOfLine && !errors.Is(err, bufio.ErrBufferFull) {
+		if name, _ := isMarker(chunk); name != "" {
+			r.next = &Header{Name: name, Line: r.line}
+			r.line++
+			r.done = true
+
+			return nil
+		}
+	}
+
+	if err == nil {
+		r.line++
+	}
+
+	r.pending = chunk
+
+	return nil
+}
+
+// readLine reads the rest of a line that did not fit in the buffer, returning the whole
+// line including the partial chunk already read. The error is nil if the line ended in
+// a newline, [io.EOF] if it ended the input or whatever else went wrong.
+func (r *Reader) readLine(chunk []byte) ([]byte, error) {
+	// The chunk belongs to the bufio.Reader and is overwritten by the next read
+	line := append([]byte(nil), chunk...)
+
+	for {
+		more, err := r.r.ReadSlice('\n')
+		line = append(line, more...)
+
+		if !errors.Is(err, bufio.ErrBufferFull) {
+			return line, err
+		}
+	}
+}
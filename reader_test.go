@@ -0,0 +1,261 @@
+package txtar_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"go.followtheprocess.codes/test"
+	"go.followtheprocess.codes/txtar"
+	gotxtar "golang.org/x/tools/txtar"
+)
+
+// entry is a file read from a txtar.Reader.
+type entry struct {
+	name     string
+	contents string
+	line     int
+}
+
+func TestReader(t *testing.T) {
+	tests := []struct {
+		name    string  // Name of the test case
+		input   string  // The archive to read
+		comment string  // Expected comment section
+		files   []entry // Expected files, in order
+	}{
+		{
+			name:    "empty",
+			input:   "",
+			comment: "",
+			files:   nil,
+		},
+		{
+			name:    "comment only",
+			input:   "A comment\nover two lines\n",
+			comment: "A comment\nover two lines\n",
+			files:   nil,
+		},
+		{
+			name:    "single file",
+			input:   "-- file1.txt --\nfile1 contents\n",
+			comment: "",
+			files: []entry{
+				{name: "file1.txt", contents: "file1 contents\n", line: 1},
+			},
+		},
+		{
+			name:    "comment and files",
+			input:   "comment\n\n-- file1.txt --\none\n\n-- dir/file2.txt --\ntwo\n",
+			comment: "comment\n\n",
+			files: []entry{
+				{name: "file1.txt", contents: "one\n\n", line: 3},
+				{name: "dir/file2.txt", contents: "two\n", line: 6},
+			},
+		},
+		{
+			name:    "empty files",
+			input:   "-- a --\n-- b --\n",
+			comment: "",
+			files: []entry{
+				{name: "a", contents: "", line: 1},
+				{name: "b", contents: "", line: 2},
+			},
+		},
+		{
+			name:    "crlf",
+			input:   "comment\r\n-- file1.txt --\r\nline 1\r\nline 2\r\n",
+			comment: "comment\n",
+			files: []entry{
+				{name: "file1.txt", contents: "line 1\nline 2\n", line: 2},
+			},
+		},
+		{
+			name:    "no trailing newline",
+			input:   "-- file1.txt --\nstuff\n-- file2.txt --",
+			comment: "",
+			files: []entry{
+				{name: "file1.txt", contents: "stuff\n", line: 1},
+				{name: "file2.txt", contents: "", line: 3},
+			},
+		},
+		{
+			name:    "not quite markers",
+			input:   "-- nope\n-- a --\n --b --\n--c --\nx -- d --\n",
+			comment: "-- nope\n",
+			files: []entry{
+				{name: "a", contents: " --b --\n--c --\nx -- d --\n", line: 2},
+			},
+		},
+		{
+			name:    "whitespace in marker",
+			input:   "--    spaced.txt    --\nstuff\n",
+			comment: "",
+			files: []entry{
+				{name: "spaced.txt", contents: "stuff\n", line: 1},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// OneByteReader makes sure nothing relies on getting a whole line in one Read
+			for name, r := range map[string]io.Reader{
+				"whole":    strings.NewReader(tt.input),
+				"one byte": iotest.OneByteReader(strings.NewReader(tt.input)),
+			} {
+				t.Run(name, func(t *testing.T) {
+					comment, files := readAll(t, txtar.NewReader(r))
+					test.Equal(t, comment, tt.comment, test.Context("Wrong comment"))
+					test.Equal(t, len(files), len(tt.files), test.Context("Wrong number of files"))
+
+					for i, want := range tt.files {
+						test.Equal(t, files[i], want)
+					}
+				})
+			}
+		})
+	}
+}
+
+func TestReaderSkip(t *testing.T) {
+	input := "comment\n-- file1.txt --\none\ntwo\n-- file2.txt --\nthree\n"
+	reader := txtar.NewReader(strings.NewReader(input))
+
+	// Calling Next straight away should skip the comment, calling it again
+	// should skip file1 without reading it
+	header, err := reader.Next()
+	test.Ok(t, err)
+	test.Equal(t, header.Name, "file1.txt")
+
+	// Read just a little bit of it
+	buf := make([]byte, 2)
+	_, err = reader.Read(buf)
+	test.Ok(t, err)
+	test.Equal(t, string(buf), "on")
+
+	header, err = reader.Next()
+	test.Ok(t, err)
+	test.Equal(t, header.Name, "file2.txt")
+	test.Equal(t, header.Line, 5)
+
+	contents, err := io.ReadAll(reader)
+	test.Ok(t, err)
+	test.Equal(t, string(contents), "three\n")
+
+	_, err = reader.Next()
+	test.ErrorIs(t, err, io.EOF)
+
+	// Should be sticky
+	_, err = reader.Next()
+	test.ErrorIs(t, err, io.EOF)
+}
+
+func TestReaderLongLines(t *testing.T) {
+	// Lines bigger than the internal buffer must come out intact, including
+	// a "\r\n" straddling the buffer boundary
+	long := strings.Repeat("x", 64*1024-1)
+	input := "-- big.txt --\n" + long + "\r\n" + long + long + "\n-- after.txt --\nsmall\n"
+
+	comment, files := readAll(t, txtar.NewReader(strings.NewReader(input)))
+	test.Equal(t, comment, "")
+	test.Equal(t, len(files), 2)
+	test.True(t, files[0].contents == long+"\n"+long+long+"\n", test.Context("Long file contents corrupted"))
+	test.Equal(t, files[1], entry{name: "after.txt", contents: "small\n", line: 4})
+}
+
+func TestReaderLongMarkers(t *testing.T) {
+	// Markers of any length are recognised, as by the original package
+	name := strings.Repeat("n", 128*1024)
+	notMarker := "-- " + strings.Repeat("x", 128*1024)
+	input := "comment\n-- " + name + " --\r\n" + notMarker + "\n-- after.txt --\nsmall\n"
+
+	comment, files := readAll(t, txtar.NewReader(strings.NewReader(input)))
+	test.Equal(t, comment, "comment\n")
+	test.Equal(t, len(files), 2)
+	test.True(t, files[0].name == name, test.Context("Long marker not recognised"))
+	test.True(t, files[0].contents == notMarker+"\n", test.Context("Long non-marker line corrupted"))
+	test.Equal(t, files[1], entry{name: "after.txt", contents: "small\n", line: 4})
+
+	// Parse sees the same files as the original
+	archive, err := txtar.ParseWith(strings.NewReader(input), txtar.Lenient())
+	test.Ok(t, err)
+
+	want := gotxtar.Parse([]byte(strings.ReplaceAll(input, "\r\n", "\n")))
+	test.Equal(t, archive.Size(), len(want.Files))
+	test.True(t, archive.Has(want.Files[0].Name), test.Context("Parse did not recognise the long marker"))
+}
+
+func TestReaderError(t *testing.T) {
+	bang := errors.New("bang")
+	reader := txtar.NewReader(iotest.ErrReader(bang))
+
+	_, err := io.ReadAll(reader)
+	test.ErrorIs(t, err, bang)
+
+	_, err = reader.Next()
+	test.ErrorIs(t, err, bang)
+}
+
+func TestReaderCompat(t *testing.T) {
+	var files []string
+	for _, dir := range []string{"TestCompat", filepath.Join("TestParse", "valid"), filepath.Join("TestParse", "invalid"), filepath.Join("TestParse", "duplicate")} {
+		matches, err := filepath.Glob(filepath.Join("testdata", dir, "*.txtar"))
+		test.Ok(t, err, test.Context("Could not glob the %s directory", dir))
+
+		files = append(files, matches...)
+	}
+
+	for _, file := range files {
+		t.Run(filepath.ToSlash(file), func(t *testing.T) {
+			contents, err := os.ReadFile(file)
+			test.Ok(t, err)
+
+			// We need to normalise line endings to get equivalent behaviour on all platforms
+			contents = bytes.ReplaceAll(contents, []byte("\r\n"), []byte("\n"))
+
+			goArchive := gotxtar.Parse(contents)
+
+			comment, entries := readAll(t, txtar.NewReader(bytes.NewReader(contents)))
+
+			// x/tools always adds a final newline
+			test.Equal(t, strings.TrimSuffix(comment, "\n"), strings.TrimSuffix(string(goArchive.Comment), "\n"))
+			test.Equal(t, len(entries), len(goArchive.Files), test.Context("Mismatch in number of files"))
+
+			for i, file := range goArchive.Files {
+				test.Equal(t, entries[i].name, file.Name)
+				test.Equal(t, strings.TrimSuffix(entries[i].contents, "\n"), strings.TrimSuffix(string(file.Data), "\n"))
+			}
+		})
+	}
+}
+
+// readAll reads the comment and every file from a txtar.Reader.
+func readAll(t *testing.T, reader *txtar.Reader) (string, []entry) {
+	t.Helper()
+
+	comment, err := io.ReadAll(reader)
+	test.Ok(t, err, test.Context("Could not read comment"))
+
+	var files []entry
+	for {
+		header, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		test.Ok(t, err, test.Context("Next returned an unexpected error"))
+
+		contents, err := io.ReadAll(reader)
+		test.Ok(t, err, test.Context("Could not read contents of %s", header.Name))
+
+		files = append(files, entry{name: header.Name, contents: string(contents), line: header.Line})
+	}
+
+	return string(comment), files
+}
@@ -0,0 +1,183 @@
+package txtar
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+)
+
+// RenameOption is a functional option for configuring [Archive.Rename] and [Archive.RenamePrefix].
+type RenameOption func(*renameConfig) error
+
+// renameConfig holds the configuration for a single rename.
+type renameConfig struct {
+	replace bool // Whether existing files may be replaced
+}
+
+// WithReplace is a [RenameOption] that allows a rename to replace any existing file
+// with the target name, rather than returning an error.
+func WithReplace() RenameOption {
+	return func(cfg *renameConfig) error {
+		cfg.replace = true
+
+		return nil
+	}
+}
+
+// Rename renames the file oldName to newName, keeping its position in the archive.
+//
+// If oldName does not exist, Rename returns an error wrapping [fs.ErrNotExist]. If
+// newName already exists, Rename returns an error wrapping [fs.ErrExist] unless
+// [WithReplace] is passed, in which case the existing file is removed.
+//
+// newName is checked according to the archive's [NamePolicy].
+func (a *Archive) Rename(oldName, newName string, options ...RenameOption) error {
+	if a == nil {
+		return errors.New("Rename called on a nil Archive")
+	}
+
+	oldName = a.key(oldName)
+
+	return a.renameFunc("Rename", oldName, options, func(name string) (string, bool) {
+		return newName, name == oldName
+	})
+}
+
+// RenamePrefix moves every file in the directory oldDir to newDir, so with an oldDir of
+// "a/b" and a newDir of "c", the file "a/b/d/e.txt" becomes "c/d/e.txt". Files keep
+// their position in the archive.
+//
+// Names are treated as slash separated paths, a file is only in oldDir if its name starts
+// with oldDir followed by a "/". An empty oldDir refers to the root, so every file is moved
+// into newDir, likewise an empty newDir moves the contents of oldDir to the root.
+//
+// If no files are in oldDir, RenamePrefix returns an error wrapping [fs.ErrNotExist].
+// If any of the new names already exist, RenamePrefix returns an error wrapping [fs.ErrExist]
+// unless [WithReplace] is passed, in which case the existing files are removed.
+//
+// RenamePrefix either renames every file or none of them, the archive is left
+// untouched if an error is returned.
+func (a *Archive) RenamePrefix(oldDir, newDir string, options ...RenameOption) error {
+	if a == nil {
+		return errors.New("RenamePrefix called on a nil Archive")
+	}
+
+	oldPrefix := dirPrefix(oldDir)
+	newPrefix := dirPrefix(newDir)
+
+	return a.renameFunc("RenamePrefix", oldDir, options, func(name string) (string, bool) {
+		rest, ok := strings.CutPrefix(name, oldPrefix)
+		if !ok {
+			return "", false
+		}
+
+		return newPrefix + rest, true
+	})
+}
+
+// renameFunc implements [Archive.Rename] and [Archive.RenamePrefix], rename is called
+// with the name of each file and returns its new name and whether it should be renamed.
+// op and source are used to describe any errors.
+//
+// Everything is checked before the archive is touched so a rename is all or nothing.
+func (a *Archive) renameFunc(op, source string, options []RenameOption, rename func(name string) (string, bool)) error {
+	cfg := renameConfig{}
+
+	var errs error
+	for _, option := range options {
+		errs = errors.Join(errs, option(&cfg))
+	}
+
+	if errs != nil {
+		return errs
+	}
+
+	// Position in a.files -> its new name, and the reverse to spot conflicts
+	renames := make(map[int]string)
+	targets := make(map[string]int)
+
+	for i, file := range a.files {
+		name, ok := rename(file.name)
+		if !ok {
+			continue
+		}
+
+		newName, err := a.checkName(name)
+		if err != nil {
+			errs = errors.Join(errs, fmt.Errorf("%s: %w", op, err))
+			continue
+		}
+
+		renames[i] = newName
+
+		// Duplicates of the same file (see DuplicateKeepAll) move together, anything
+		// else landing on the same name is a conflict
+		if j, exists := targets[newName]; exists && a.files[j].name != file.name {
+			errs = errors.Join(errs, fmt.Errorf("%s: %q and %q would both be renamed to %q", op, a.files[j].name, file.name, newName))
+			continue
+		}
+
+		targets[newName] = i
+	}
+
+	if errs != nil {
+		return errs
+	}
+
+	if len(renames) == 0 {
+		return fmt.Errorf("%s: %q: %w", op, source, fs.ErrNotExist)
+	}
+
+	// Existing files in the way, that aren't themselves being moved
+	replaced := make(map[int]bool)
+
+	for i, file := range a.files {
+		if _, moving := renames[i]; moving {
+			continue
+		}
+
+		if _, conflict := targets[file.name]; conflict {
+			if !cfg.replace {
+				errs = errors.Join(errs, fmt.Errorf("%s: %q: %w", op, file.name, fs.ErrExist))
+				continue
+			}
+
+			replaced[i] = true
+		}
+	}
+
+	if errs != nil {
+		return errs
+	}
+
+	files := a.files[:0]
+	for i, file := range a.files {
+		if replaced[i] {
+			continue
+		}
+
+		if newName, ok := renames[i]; ok {
+			file.name = newName
+		}
+
+		files = append(files, file)
+	}
+
+	clear(a.files[len(files):])
+	a.files = files
+	a.reindex()
+
+	return nil
+}
+
+// dirPrefix returns the prefix shared by all names in the slash separated directory dir,
+// which is "" for the root.
+func dirPrefix(dir string) string {
+	dir = strings.Trim(strings.TrimSpace(dir), "/")
+	if dir == "" || dir == "." {
+		return ""
+	}
+
+	return dir + "/"
+}
@@ -0,0 +1,224 @@
+package txtar_test
+
+import (
+	"io/fs"
+	"slices"
+	"strings"
+	"testing"
+
+	"go.followtheprocess.codes/test"
+	"go.followtheprocess.codes/txtar"
+)
+
+func TestRename(t *testing.T) {
+	tests := []struct {
+		wantErr error                // Expected error, if any
+		name    string               // Name of the test case
+		oldName string               // Name to rename from
+		newName string               // Name to rename to
+		options []txtar.RenameOption // Options to pass to Rename
+		want    []string             // Expected file names in order afterwards
+	}{
+		{
+			name:    "keeps position",
+			oldName: "b.txt",
+			newName: "renamed.txt",
+			want:    []string{"a.txt", "renamed.txt", "c.txt"},
+		},
+		{
+			name:    "same name",
+			oldName: "a.txt",
+			newName: " a.txt ",
+			want:    []string{"a.txt", "b.txt", "c.txt"},
+		},
+		{
+			name:    "missing",
+			oldName: "missing.txt",
+			newName: "d.txt",
+			wantErr: fs.ErrNotExist,
+		},
+		{
+			name:    "target exists",
+			oldName: "a.txt",
+			newName: "c.txt",
+			wantErr: fs.ErrExist,
+		},
+		{
+			name:    "replace",
+			oldName: "c.txt",
+			newName: "a.txt",
+			options: []txtar.RenameOption{txtar.WithReplace()},
+			want:    []string{"b.txt", "a.txt"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			archive := renameArchive(t, "a.txt", "b.txt", "c.txt")
+			before := archive.String()
+
+			err := archive.Rename(tt.oldName, tt.newName, tt.options...)
+			if tt.wantErr != nil {
+				test.ErrorIs(t, err, tt.wantErr)
+				test.Diff(t, archive.String(), before)
+
+				return
+			}
+
+			test.Ok(t, err)
+			test.EqualFunc(t, names(archive), tt.want, slices.Equal)
+
+			// Contents should have moved with the name
+			contents, ok := archive.Read(tt.newName)
+			test.True(t, ok)
+			test.Equal(t, contents, strings.TrimSpace(tt.oldName)+" contents\n")
+		})
+	}
+}
+
+func TestRenamePolicy(t *testing.T) {
+	archive, err := txtar.New(
+		txtar.WithNamePolicy(txtar.NameClean),
+		txtar.WithFile("a.txt", "a"),
+	)
+	test.Ok(t, err)
+
+	test.ErrorIs(t, archive.Rename("a.txt", "../escape.txt"), txtar.ErrEscapingName)
+	test.Ok(t, archive.Rename(`.\a.txt`, `dir\b.txt`))
+	test.True(t, archive.Has("dir/b.txt"))
+}
+
+func TestRenamePrefix(t *testing.T) {
+	tests := []struct {
+		wantErr error                // Expected error, if any
+		name    string               // Name of the test case
+		oldDir  string               // Directory to move from
+		newDir  string               // Directory to move to
+		options []txtar.RenameOption // Options to pass to RenamePrefix
+		want    []string             // Expected file names in order afterwards
+	}{
+		{
+			name:   "simple",
+			oldDir: "a",
+			newDir: "z",
+			want:   []string{"top.txt", "z/one.txt", "ab/two.txt", "z/b/three.txt", "c/three.txt"},
+		},
+		{
+			name:   "nested",
+			oldDir: "a/b/",
+			newDir: "c/d",
+			want:   []string{"top.txt", "a/one.txt", "ab/two.txt", "c/d/three.txt", "c/three.txt"},
+		},
+		{
+			name:   "to root",
+			oldDir: "a",
+			newDir: "",
+			want:   []string{"top.txt", "one.txt", "ab/two.txt", "b/three.txt", "c/three.txt"},
+		},
+		{
+			name:   "from root",
+			oldDir: "",
+			newDir: "root",
+			want: []string{
+				"root/top.txt",
+				"root/a/one.txt",
+				"root/ab/two.txt",
+				"root/a/b/three.txt",
+				"root/c/three.txt",
+			},
+		},
+		{
+			name:   "into itself",
+			oldDir: "a",
+			newDir: "a/a",
+			want:   []string{"top.txt", "a/a/one.txt", "ab/two.txt", "a/a/b/three.txt", "c/three.txt"},
+		},
+		{
+			name:    "missing",
+			oldDir:  "nope",
+			newDir:  "z",
+			wantErr: fs.ErrNotExist,
+		},
+		{
+			name:    "not a prefix match",
+			oldDir:  "a/one.txt",
+			newDir:  "z",
+			wantErr: fs.ErrNotExist,
+		},
+		{
+			name:    "conflict",
+			oldDir:  "a/b",
+			newDir:  "c",
+			wantErr: fs.ErrExist,
+		},
+		{
+			name:    "replace",
+			oldDir:  "a/b",
+			newDir:  "c",
+			options: []txtar.RenameOption{txtar.WithReplace()},
+			want:    []string{"top.txt", "a/one.txt", "ab/two.txt", "c/three.txt"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			archive := renameArchive(t, "top.txt", "a/one.txt", "ab/two.txt", "a/b/three.txt", "c/three.txt")
+			before := archive.String()
+
+			err := archive.RenamePrefix(tt.oldDir, tt.newDir, tt.options...)
+			if tt.wantErr != nil {
+				test.ErrorIs(t, err, tt.wantErr)
+				test.Diff(t, archive.String(), before)
+
+				return
+			}
+
+			test.Ok(t, err)
+			test.EqualFunc(t, names(archive), tt.want, slices.Equal)
+		})
+	}
+}
+
+func TestRenameDuplicates(t *testing.T) {
+	archive, err := txtar.ParseWith(
+		strings.NewReader("-- a.txt --\none\n-- b.txt --\n-- a.txt --\ntwo\n"),
+		txtar.OnDuplicate(txtar.DuplicateKeepAll),
+	)
+	test.Ok(t, err)
+
+	test.Ok(t, archive.Rename("a.txt", "c.txt"))
+	test.EqualFunc(t, names(archive), []string{"c.txt", "b.txt", "c.txt"}, slices.Equal)
+	test.False(t, archive.Has("a.txt"))
+}
+
+func TestRenameNil(t *testing.T) {
+	var archive *txtar.Archive
+
+	test.Err(t, archive.Rename("a", "b"))
+	test.Err(t, archive.RenamePrefix("a", "b"))
+}
+
+// renameArchive returns an archive containing the named files, each with contents
+// of "<name> contents".
+func renameArchive(t *testing.T, files ...string) *txtar.Archive {
+	t.Helper()
+
+	archive, err := txtar.New()
+	test.Ok(t, err)
+
+	for _, name := range files {
+		test.Ok(t, archive.Write(name, name+" contents"))
+	}
+
+	return archive
+}
+
+// names returns the names of the files in archive, in order.
+func names(archive *txtar.Archive) []string {
+	var names []string
+	for name := range archive.Files() {
+		names = append(names, name)
+	}
+
+	return names
+}
@@ -0,0 +1,275 @@
+package txtar
+
+import (
+	"io"
+	"io/fs"
+	"iter"
+	"sync"
+)
+
+// SyncArchive is an [Archive] that is safe for concurrent use by multiple goroutines,
+// e.g. a fixture shared between parallel subtests.
+//
+// It provides the same API as [Archive], with every method guarded by a [sync.RWMutex] so
+// reads may happen in parallel while writes have exclusive access. Iterators such as
+// [SyncArchive.Files] range over a consistent snapshot taken when iteration starts, so
+// the archive may be freely modified from inside the loop.
+//
+// Anything not covered by a method, e.g. passing the archive to [Dump] or [Diff], can be
+// done while holding the lock with [SyncArchive.View] or [SyncArchive.Update].
+//
+// The zero value is an empty archive ready to use. A SyncArchive must not be copied
+// after first use.
+type SyncArchive struct {
+	archive *Archive     // The archive being guarded, nil means empty
+	mu      sync.RWMutex // Guards archive
+}
+
+// NewSync returns a [SyncArchive] holding a copy of archive (see [Archive.Clone]), so
+// later changes to archive itself are not reflected in it. A nil archive gives an empty
+// [SyncArchive].
+func NewSync(archive *Archive) *SyncArchive {
+	return &SyncArchive{archive: archive.Clone()}
+}
+
+// View calls fn with the underlying [Archive] while holding the read lock, returning
+// any error from fn.
+//
+// fn must not modify the archive, or keep a reference to it after returning, and must
+// not call any other method on the [SyncArchive] as that may deadlock.
+func (s *SyncArchive) View(fn func(archive *Archive) error) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return fn(s.view())
+}
+
+// Update calls fn with the underlying [Archive] while holding the write lock, returning
+// any error from fn.
+//
+// fn must not keep a reference to the archive after returning, and must not call any
+// other method on the [SyncArchive] as that will deadlock.
+func (s *SyncArchive) Update(fn func(archive *Archive) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return fn(s.get())
+}
+
+// Comment returns the archive comment, see [Archive.Comment].
+func (s *SyncArchive) Comment() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.archive.Comment()
+}
+
+// Has returns whether the archive contains a file with the given name, see [Archive.Has].
+func (s *SyncArchive) Has(name string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.archive.Has(name)
+}
+
+// Read returns the contents of the named file, see [Archive.Read].
+func (s *SyncArchive) Read(name string) (value string, ok bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.archive.Read(name)
+}
+
+// Size returns the number of files in the archive, see [Archive.Size].
+func (s *SyncArchive) Size() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.archive.Size()
+}
+
+// String returns the serialised archive, see [Archive.String].
+func (s *SyncArchive) String() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.archive.String()
+}
+
+// Files returns an iterator over the archive's filenames and contents, see [Archive.Files].
+//
+// The files yielded are those in the archive when iteration starts, unaffected by any
+// changes made during it.
+func (s *SyncArchive) Files() iter.Seq2[string, string] {
+	return func(yield func(string, string) bool) {
+		s.Snapshot().Files()(yield)
+	}
+}
+
+// Duplicates returns an iterator over the duplicated files in the archive, see
+// [Archive.Duplicates].
+//
+// The files yielded are those in the archive when iteration starts, unaffected by any
+// changes made during it.
+func (s *SyncArchive) Duplicates() iter.Seq2[string, string] {
+	return func(yield func(string, string) bool) {
+		s.Clone().Duplicates()(yield)
+	}
+}
+
+// FS returns a snapshot of the archive as an [fs.FS], see [Archive.FS].
+func (s *SyncArchive) FS() fs.FS {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.archive.FS()
+}
+
+// Extract writes every file in the archive to disk under dir, see [Archive.Extract].
+func (s *SyncArchive) Extract(dir string, options ...ExtractOption) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.view().Extract(dir, options...)
+}
+
+// Clone returns a deep copy of the archive as a plain [Archive], see [Archive.Clone].
+func (s *SyncArchive) Clone() *Archive {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.view().Clone()
+}
+
+// Snapshot returns a read-only [Snapshot] of the archive's current contents, see
+// [Archive.Snapshot].
+func (s *SyncArchive) Snapshot() Snapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.archive.Snapshot()
+}
+
+// Write writes a named file with contents to the archive, see [Archive.Write].
+func (s *SyncArchive) Write(name, contents string) error {
+	return s.Update(func(archive *Archive) error {
+		return archive.Write(name, contents)
+	})
+}
+
+// Delete removes a file from the archive, see [Archive.Delete].
+func (s *SyncArchive) Delete(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.archive.Delete(name)
+}
+
+// Rename renames a file in the archive, see [Archive.Rename].
+func (s *SyncArchive) Rename(oldName, newName string, options ...RenameOption) error {
+	return s.Update(func(archive *Archive) error {
+		return archive.Rename(oldName, newName, options...)
+	})
+}
+
+// RenamePrefix moves every file under one directory to another, see [Archive.RenamePrefix].
+func (s *SyncArchive) RenamePrefix(oldDir, newDir string, options ...RenameOption) error {
+	return s.Update(func(archive *Archive) error {
+		return archive.RenamePrefix(oldDir, newDir, options...)
+	})
+}
+
+// Sort sorts the files in the archive by name, see [Archive.Sort].
+func (s *SyncArchive) Sort() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.archive.Sort()
+}
+
+// SortFunc sorts the files in the archive by name using cmp, see [Archive.SortFunc].
+//
+// cmp is called while holding the write lock so must not call any method on the [SyncArchive].
+func (s *SyncArchive) SortFunc(cmp func(a, b string) int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.archive.SortFunc(cmp)
+}
+
+// MoveBefore moves the named file to just before target, see [Archive.MoveBefore].
+func (s *SyncArchive) MoveBefore(name, target string) error {
+	return s.Update(func(archive *Archive) error {
+		return archive.MoveBefore(name, target)
+	})
+}
+
+// MoveAfter moves the named file to just after target, see [Archive.MoveAfter].
+func (s *SyncArchive) MoveAfter(name, target string) error {
+	return s.Update(func(archive *Archive) error {
+		return archive.MoveAfter(name, target)
+	})
+}
+
+// InsertAt writes a named file to the archive at position i, see [Archive.InsertAt].
+func (s *SyncArchive) InsertAt(i int, name, contents string) error {
+	return s.Update(func(archive *Archive) error {
+		return archive.InsertAt(i, name, contents)
+	})
+}
+
+// Merge merges the files from src into the archive, see [Archive.Merge].
+//
+// src must not be modified by another goroutine while the merge is in progress, and a
+// resolver given with [WithResolver] must not call any method on the [SyncArchive].
+func (s *SyncArchive) Merge(src *Archive, options ...MergeOption) error {
+	return s.Update(func(archive *Archive) error {
+		return archive.Merge(src, options...)
+	})
+}
+
+// Apply applies the changes described by d to the archive, see [Archive.Apply].
+func (s *SyncArchive) Apply(d *ArchiveDiff) error {
+	return s.Update(func(archive *Archive) error {
+		return archive.Apply(d)
+	})
+}
+
+// ApplyPatch applies a unified diff read from r to the archive, see [Archive.ApplyPatch].
+//
+// The patch is read and parsed in full before the lock is taken.
+func (s *SyncArchive) ApplyPatch(r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	changes, err := parsePatch(string(data))
+	if err != nil {
+		return err
+	}
+
+	return s.Update(func(archive *Archive) error {
+		return archive.applyChanges(changes)
+	})
+}
+
+// view returns the guarded archive for reading, or a new empty one if there isn't one
+// yet (the zero value), the caller must hold at least the read lock.
+func (s *SyncArchive) view() *Archive {
+	if s.archive == nil {
+		return &Archive{}
+	}
+
+	return s.archive
+}
+
+// get returns the guarded archive for writing, creating it first if necessary, the
+// caller must hold the write lock.
+func (s *SyncArchive) get() *Archive {
+	if s.archive == nil {
+		s.archive = &Archive{}
+	}
+
+	return s.archive
+}
@@ -0,0 +1,129 @@
+package txtar_test
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.followtheprocess.codes/test"
+	"go.followtheprocess.codes/txtar"
+)
+
+func TestSyncArchive(t *testing.T) {
+	archive, err := txtar.New(
+		txtar.WithComment("comment"),
+		txtar.WithFile("a.txt", "a"),
+		txtar.WithFile("b.txt", "b"),
+	)
+	test.Ok(t, err)
+
+	s := txtar.NewSync(archive)
+
+	// It holds a copy
+	archive.Delete("a.txt")
+	test.True(t, s.Has("a.txt"))
+
+	test.Equal(t, s.Comment(), "comment")
+	test.Equal(t, s.Size(), 2)
+
+	test.Ok(t, s.Write("c.txt", "c"))
+	test.Ok(t, s.Rename("c.txt", "d.txt"))
+	test.Ok(t, s.MoveBefore("d.txt", "a.txt"))
+	test.Ok(t, s.InsertAt(1, "e.txt", "e"))
+	s.Delete("b.txt")
+
+	test.Diff(t, s.String(), "comment\n\n-- d.txt --\nc\n-- e.txt --\ne\n-- a.txt --\na\n")
+
+	s.Sort()
+
+	contents, ok := s.Read("e.txt")
+	test.True(t, ok)
+	test.Equal(t, contents, "e\n")
+
+	// Modifying the archive from inside the loop is fine, iteration sees a snapshot
+	var names []string
+	for name := range s.Files() {
+		names = append(names, name)
+		test.Ok(t, s.Write("new-"+name, "new"))
+	}
+
+	test.EqualFunc(t, names, []string{"a.txt", "d.txt", "e.txt"}, slices.Equal)
+	test.Equal(t, s.Size(), 6)
+
+	// View and Update give access to the whole API
+	test.Ok(t, s.View(func(archive *txtar.Archive) error {
+		test.Equal(t, archive.Size(), 6)
+		return nil
+	}))
+
+	test.Ok(t, s.Update(func(archive *txtar.Archive) error {
+		return txtar.WithComment("updated")(archive)
+	}))
+	test.Equal(t, s.Comment(), "updated")
+
+	test.Ok(t, s.ApplyPatch(strings.NewReader("--- a/a.txt\n+++ b/a.txt\n@@ -1 +1 @@\n-a\n+A\n")))
+
+	contents, ok = s.Read("a.txt")
+	test.True(t, ok)
+	test.Equal(t, contents, "A\n")
+
+	// The zero value is ready to use
+	var empty txtar.SyncArchive
+	test.Equal(t, empty.Size(), 0)
+	test.Equal(t, empty.Clone().Size(), 0)
+	test.Ok(t, empty.Write("a.txt", "a"))
+	test.True(t, empty.Has("a.txt"))
+}
+
+// Run with -race to be of any use.
+func TestSyncArchiveConcurrent(t *testing.T) {
+	archive, err := txtar.New(txtar.WithComment("comment"), txtar.WithFile("shared.txt", "shared"))
+	test.Ok(t, err)
+
+	s := txtar.NewSync(archive)
+
+	const workers = 8
+
+	// The test helpers call t.Fatal which must only happen on the test goroutine,
+	// so the workers report failures with t.Errorf instead
+	var wg sync.WaitGroup
+	for worker := range workers {
+		wg.Go(func() {
+			for i := range 50 {
+				name := fmt.Sprintf("worker%d/%d.txt", worker, i)
+				if err := s.Write(name, name); err != nil {
+					t.Errorf("Write(%q): %v", name, err)
+				}
+
+				if contents, ok := s.Read(name); !ok || contents != name+"\n" {
+					t.Errorf("Read(%q) = %q, %v, want %q, true", name, contents, ok, name+"\n")
+				}
+
+				if _, ok := s.Read("shared.txt"); !ok {
+					t.Error("shared.txt missing")
+				}
+
+				for range s.Files() {
+				}
+
+				_ = s.String()
+				_ = s.Size()
+
+				if i%2 == 0 {
+					s.Delete(name)
+
+					if s.Has(name) {
+						t.Errorf("%s still present after Delete", name)
+					}
+				}
+			}
+		})
+	}
+
+	wg.Wait()
+
+	test.Equal(t, s.Size(), 1+workers*25)
+	test.Equal(t, s.Comment(), "comment")
+}
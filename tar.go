@@ -0,0 +1,153 @@
+package txtar
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+const (
+	memberMode = 0o644 // Mode of every file written to a tar or zip file
+	paxComment = "comment"
+)
+
+// memberTime is the modification time of every file written to a tar or zip file, so the
+// output depends only on the archive's contents. It is the earliest time a zip file can
+// represent.
+var memberTime = time.Date(1980, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// DumpTar writes the [Archive] to w as a tar file, applying any number of [DumpOption].
+//
+// Every file becomes a regular file member with mode 0644, owned by uid and gid 0 and
+// modified at midnight on 1 January 1980 UTC, in the order they would be serialised
+// by [Dump]. With the headers fixed, the output depends only on the archive so is
+// reproducible. The archive comment, if there is one, is stored as the "comment" record
+// of a PAX global header, which [FromTar] restores.
+//
+// File names are validated and normalised with [ValidName] as tar files containing
+// absolute or escaping paths are a hazard to extract, an invalid name is an error.
+func DumpTar(w io.Writer, archive *Archive, options ...DumpOption) error {
+	if archive == nil {
+		return errors.New("DumpTar: archive was nil")
+	}
+
+	cfg := dumpConfig{}
+
+	var errs error
+	for _, option := range options {
+		errs = errors.Join(errs, option(&cfg))
+	}
+
+	if errs != nil {
+		return errs
+	}
+
+	tw := tar.NewWriter(w)
+
+	if archive.comment != "" {
+		header := &tar.Header{
+			Typeflag:   tar.TypeXGlobalHeader,
+			PAXRecords: map[string]string{paxComment: archive.comment},
+		}
+
+		if err := tw.WriteHeader(header); err != nil {
+			return fmt.Errorf("DumpTar: %w", err)
+		}
+	}
+
+	for _, file := range archive.ordered(archive.sorted || cfg.sorted) {
+		name, err := ValidName(file.name)
+		if err != nil {
+			return fmt.Errorf("DumpTar: %w", err)
+		}
+
+		header := &tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     name,
+			Mode:     memberMode,
+			Size:     int64(len(file.contents)),
+			ModTime:  memberTime,
+		}
+
+		if err := tw.WriteHeader(header); err != nil {
+			return fmt.Errorf("DumpTar: %s: %w", name, err)
+		}
+
+		if _, err := io.WriteString(tw, file.contents); err != nil {
+			return fmt.Errorf("DumpTar: %s: %w", name, err)
+		}
+	}
+
+	return tw.Close()
+}
+
+// FromTar builds an [Archive] from the tar file read from r, applying any number of
+// options to control which files are included exactly as for [FromFS].
+//
+// Regular files are added in the order they appear in the tar file, everything else
+// (directories, links etc.) is skipped. Names are validated and normalised with
+// [ValidName] so an absolute or escaping name is an error, and as when extracting a
+// tar file, a later member with the same name as an earlier one replaces its contents.
+// A "comment" record in a PAX global header, as written by [DumpTar], becomes
+// the archive comment.
+//
+// Like [FromFS], FromTar attempts every file and reports all the errors together,
+// except for errors reading the tar file itself which stop it immediately.
+func FromTar(r io.Reader, options ...FromOption) (*Archive, error) {
+	cfg := fromConfig{}
+
+	var errs error
+	for _, option := range options {
+		errs = errors.Join(errs, option(&cfg))
+	}
+
+	if errs != nil {
+		return nil, errs
+	}
+
+	archive := &Archive{markers: cfg.markers}
+	tr := tar.NewReader(r)
+
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("FromTar: %w", err)
+		}
+
+		if header.Typeflag == tar.TypeXGlobalHeader {
+			if comment, ok := header.PAXRecords[paxComment]; ok {
+				errs = errors.Join(errs, WithComment(comment)(archive))
+			}
+
+			continue
+		}
+
+		if !header.FileInfo().Mode().IsRegular() {
+			continue
+		}
+
+		open := func() (io.Reader, error) { return tr, nil }
+
+		name, contents, ok, err := readMember(header.Name, header.Size, open, cfg)
+		if err != nil {
+			errs = errors.Join(errs, fmt.Errorf("FromTar: %s: %w", header.Name, err))
+			continue
+		}
+
+		if ok {
+			errs = errors.Join(errs, archive.Write(name, contents))
+		}
+	}
+
+	if errs != nil {
+		return nil, errs
+	}
+
+	return archive, nil
+}
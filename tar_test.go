@@ -0,0 +1,199 @@
+package txtar_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"io"
+	"slices"
+	"testing"
+	"time"
+
+	"go.followtheprocess.codes/test"
+	"go.followtheprocess.codes/txtar"
+)
+
+func TestTarRoundTrip(t *testing.T) {
+	archive, err := txtar.New(
+		txtar.WithComment("A comment\nover lines"),
+		txtar.WithFile("z.txt", "z"),
+		txtar.WithFile("dir/a.txt", "a"),
+		txtar.WithFile("empty.txt", ""),
+	)
+	test.Ok(t, err)
+
+	buf := &bytes.Buffer{}
+	test.Ok(t, txtar.DumpTar(buf, archive))
+
+	got, err := txtar.FromTar(bytes.NewReader(buf.Bytes()))
+	test.Ok(t, err)
+	test.True(t, txtar.Equal(got, archive), test.Context("Mismatch: %v", txtar.Mismatch(got, archive)))
+
+	// Sorted works as for Dump
+	buf.Reset()
+	test.Ok(t, txtar.DumpTar(buf, archive, txtar.Sorted()))
+
+	got, err = txtar.FromTar(buf)
+	test.Ok(t, err)
+	test.EqualFunc(t, names(got), []string{"dir/a.txt", "empty.txt", "z.txt"}, slices.Equal)
+}
+
+func TestDumpTarDeterministic(t *testing.T) {
+	dump := func() []byte {
+		archive, err := txtar.New(txtar.WithComment("comment"), txtar.WithFile("a.txt", "a"), txtar.WithFile("b.txt", "b"))
+		test.Ok(t, err)
+
+		buf := &bytes.Buffer{}
+		test.Ok(t, txtar.DumpTar(buf, archive))
+
+		return buf.Bytes()
+	}
+
+	first := dump()
+	test.True(t, bytes.Equal(first, dump()), test.Context("DumpTar output is not reproducible"))
+
+	tr := tar.NewReader(bytes.NewReader(first))
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		test.Ok(t, err)
+
+		// The global header holding the comment has no time of its own
+		if header.Typeflag == tar.TypeReg {
+			test.True(t, header.ModTime.Equal(time.Date(1980, time.January, 1, 0, 0, 0, 0, time.UTC)))
+			test.Equal(t, header.Mode, 0o644)
+			test.Equal(t, header.Uid, 0)
+			test.Equal(t, header.Gid, 0)
+		}
+	}
+}
+
+func TestDumpTarErrors(t *testing.T) {
+	test.Err(t, txtar.DumpTar(io.Discard, nil))
+
+	archive, err := txtar.New(txtar.WithFile("../escape.txt", "nope"))
+	test.Ok(t, err)
+
+	err = txtar.DumpTar(io.Discard, archive)
+	test.ErrorIs(t, err, txtar.ErrEscapingName)
+}
+
+func TestFromTar(t *testing.T) {
+	tests := []struct {
+		wantErr error              // Expected error, if any
+		name    string             // Name of the test case
+		options []txtar.FromOption // Options to pass to FromTar
+		want    []string           // Expected file names in order
+	}{
+		{
+			name: "default",
+			want: []string{"b.txt", "a.txt", "dir/c.go", "vendor/d.go"},
+		},
+		{
+			name:    "exclude directory",
+			options: []txtar.FromOption{txtar.WithExclude("vendor")},
+			want:    []string{"b.txt", "a.txt", "dir/c.go"},
+		},
+		{
+			name:    "include",
+			options: []txtar.FromOption{txtar.WithInclude("*.go")},
+			want:    []string{"dir/c.go", "vendor/d.go"},
+		},
+		{
+			name:    "too large",
+			options: []txtar.FromOption{txtar.WithMaxSize(5)},
+			wantErr: txtar.ErrFileTooLarge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := buildTar(t, []tarMember{
+				{header: tar.Header{Typeflag: tar.TypeDir, Name: "dir/"}},
+				{header: tar.Header{Typeflag: tar.TypeReg, Name: "./b.txt"}, contents: "b"},
+				{header: tar.Header{Typeflag: tar.TypeReg, Name: "a.txt"}, contents: "first"},
+				{header: tar.Header{Typeflag: tar.TypeSymlink, Name: "link", Linkname: "a.txt"}},
+				{header: tar.Header{Typeflag: tar.TypeReg, Name: "dir/c.go"}, contents: "package c"},
+				{header: tar.Header{Typeflag: tar.TypeReg, Name: "vendor/d.go"}, contents: "package d"},
+				{header: tar.Header{Typeflag: tar.TypeReg, Name: "a.txt"}, contents: "second"},
+			})
+
+			archive, err := txtar.FromTar(bytes.NewReader(data), tt.options...)
+			if tt.wantErr != nil {
+				test.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			test.Ok(t, err)
+			test.EqualFunc(t, names(archive), tt.want, slices.Equal)
+
+			// Later members win, like extracting
+			if archive.Has("a.txt") {
+				contents, _ := archive.Read("a.txt")
+				test.Equal(t, contents, "second\n")
+			}
+		})
+	}
+}
+
+func TestFromTarBinary(t *testing.T) {
+	data := buildTar(t, []tarMember{
+		{header: tar.Header{Typeflag: tar.TypeReg, Name: "text.txt"}, contents: "text"},
+		{header: tar.Header{Typeflag: tar.TypeReg, Name: "image.png"}, contents: "\x89PNG\x00\x00"},
+	})
+
+	_, err := txtar.FromTar(bytes.NewReader(data))
+	test.ErrorIs(t, err, txtar.ErrBinaryFile)
+
+	archive, err := txtar.FromTar(bytes.NewReader(data), txtar.WithBinary(txtar.BinarySkip))
+	test.Ok(t, err)
+	test.EqualFunc(t, names(archive), []string{"text.txt"}, slices.Equal)
+}
+
+func TestFromTarUnsafe(t *testing.T) {
+	for _, name := range []string{"../escape.txt", "/etc/passwd"} {
+		t.Run(name, func(t *testing.T) {
+			data := buildTar(t, []tarMember{
+				{header: tar.Header{Typeflag: tar.TypeReg, Name: name}, contents: "nope"},
+			})
+
+			_, err := txtar.FromTar(bytes.NewReader(data))
+			test.Err(t, err)
+			test.ErrorAs[*txtar.NameError](t, err)
+		})
+	}
+
+	_, err := txtar.FromTar(bytes.NewReader([]byte("not a tar file, but long enough to have a go at reading a header from it")))
+	test.Err(t, err)
+}
+
+// tarMember is a single member of a tar file built by buildTar.
+type tarMember struct {
+	header   tar.Header // Header, the size is filled in from contents
+	contents string     // Contents of a regular file
+}
+
+// buildTar returns a tar file made of the given members.
+func buildTar(t *testing.T, members []tarMember) []byte {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+
+	for _, member := range members {
+		member.header.Size = int64(len(member.contents))
+		member.header.Mode = 0o644
+
+		test.Ok(t, tw.WriteHeader(&member.header))
+
+		_, err := io.WriteString(tw, member.contents)
+		test.Ok(t, err)
+	}
+
+	test.Ok(t, tw.Close())
+
+	return buf.Bytes()
+}
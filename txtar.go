@@ -12,11 +12,35 @@
 //
 //   - Files stored in the archive may be looked up by name and operated on individually
 //   - Methods and functions are provided to help easily facilitate individual file editing
+//   - Files can be renamed, and whole directories moved, in place with [Archive.Rename] and [Archive.RenamePrefix]
 //   - An ergonomic API for constructing an [Archive], rather than simply exposing struct fields
-//   - File names and contents are stored with all leading and trailing whitespace trimmed so that formatting the archive is easier and more consistent
-//   - Parsing an [Archive] from its serialised format *can* error in the presence of a malformed document
+//   - File names and contents are stored with all leading and trailing whitespace trimmed so that formatting the archive is easier and more consistent,
+//     use [WithVerbatim] or [Verbatim] to preserve contents exactly
+//   - Parsing an [Archive] from its serialised format *can* error in the presence of a malformed document,
+//     use [Lenient] to accept everything the original package accepts
 //   - [Parse] accepts an [io.Reader] rather than a []byte
+//   - Contents that would be misparsed as a file marker are rejected rather than silently splitting the file, or escaped with
+//     [MarkerQuote] and restored by [Quoted], see [MarkerPolicy]
+//   - File names can be validated and normalised with [ValidName], see [NamePolicy]
+//   - Files with duplicate names are kept as the original does, or [OnDuplicate] can reject them or keep just one, see [DuplicatePolicy]
 //   - [Dump] is provided to serialise an [Archive] to an [io.Writer]
+//   - [Archive.FS] presents an archive as a read-only [io/fs.FS], so it works with [io/fs.WalkDir], [io/fs.Glob] and anything else that accepts one
+//   - [Archive.Extract] writes an archive to disk safely, rejecting names that would escape the target directory
+//   - [FromFS] and [FromDir] build an archive from a directory tree, with include and exclude patterns, size limits and a [BinaryPolicy]
+//   - [EqualWith] compares archives ignoring order, whitespace, the comment etc. and [Mismatch] explains why they differ
+//   - [Diff] describes the differences between two archives and renders them as a unified diff
+//   - Changes can be applied to an archive from a [Diff] or a unified diff with [Archive.Apply] and [Archive.ApplyPatch]
+//   - [Archive.Clone] makes a deep copy and [Archive.Snapshot] a read-only view, for sharing fixtures safely
+//   - [SyncArchive] wraps an archive for safe concurrent use, e.g. by parallel tests
+//   - Archives can be layered with [Archive.Merge], choosing how conflicting files and comments are combined
+//   - The order of files is under your control with [Archive.Sort], [Archive.MoveBefore] etc. and
+//     [WithSorted] gives canonical, sorted output
+//   - [Archive] implements [encoding.TextMarshaler], [encoding.TextUnmarshaler], [io.WriterTo] and [io.ReaderFrom]
+//   - Archives marshal to and from JSON with a stable, documented schema, see [Archive.MarshalJSON]
+//   - Archives convert to and from tar and zip files with [DumpTar], [DumpZip], [FromTar] and [FromZip]
+//   - [FromGoArchive] and [Archive.ToGoArchive] convert to and from the original package's types for incremental migration
+//   - [Format] canonicalises a document, as does the txtar fmt command for whole directories
+//   - [Reader] and [Writer] are provided to stream the files in an archive without holding it all in memory
 //   - File contents are represented as strings, not []byte for a more convenient format
 //
 // # Original Package Documentation
@@ -53,6 +77,7 @@
 package txtar // import "go.followtheprocess.codes/txtar"
 
 import (
+	"bufio"
 	"bytes"
 	"errors"
 	"io"
@@ -62,10 +87,13 @@ import (
 	"strings"
 )
 
+// copyBufferSize is the size of the buffer used to copy each file's contents out of
+// the [Reader] during parsing.
+const copyBufferSize = 32 * 1024
+
 var (
-	newlineMarker = []byte("\n-- ")
-	marker        = []byte("-- ")
-	markerEnd     = []byte(" --")
+	marker    = []byte("-- ")
+	markerEnd = []byte(" --")
 )
 
 // A file represents a txtar archive file.
@@ -80,10 +108,15 @@ type file struct {
 // an ergonomic API to read, write and delete individual files.
 //
 // An Archive is not safe for concurrent access across multiple goroutines, the caller
-// is responsible for synchronising concurrent access.
+// is responsible for synchronising concurrent access, or use a [SyncArchive].
 type Archive struct {
-	comment string
-	files   []file
+	comment  string
+	files    []file
+	index    map[string]int // Name to position in files (of the first, if duplicated)
+	markers  MarkerPolicy   // How to handle text that looks like a file marker
+	names    NamePolicy     // How to validate file names
+	verbatim bool           // Store the comment and file contents as given, rather than trimming whitespace
+	sorted   bool           // Serialise files sorted by name, rather than in archive order
 }
 
 // Comment returns the top level archive comment.
@@ -101,15 +134,9 @@ func (a *Archive) Has(name string) bool {
 		return false
 	}
 
-	name = strings.TrimSpace(name)
+	_, ok := a.find(a.key(name))
 
-	for _, file := range a.files {
-		if file.name == name {
-			return true
-		}
-	}
-
-	return false
+	return ok
 }
 
 // Write writes a named file with contents to the archive.
@@ -118,25 +145,41 @@ func (a *Archive) Has(name string) bool {
 // overwrite the contents of that file.
 //
 // The file contents will have leading and trailing whitespace trimmed so that
-// formatting can be kept consistent when parsing and serialising an archive.
+// formatting can be kept consistent when parsing and serialising an archive, unless
+// the archive was created with [WithVerbatim] in which case they are stored as is.
+//
+// If the contents contain a line that would be parsed as a file marker, Write returns
+// a [*MarkerError] unless the archive's [MarkerPolicy] says otherwise. Likewise, the
+// name is checked according to the archive's [NamePolicy], which may return a [*NameError].
 func (a *Archive) Write(name, contents string) error {
 	if a == nil {
 		return errors.New("Write called on a nil Archive")
 	}
 
-	name = strings.TrimSpace(name)
-	contents = strings.TrimSpace(contents)
+	name, err := a.checkName(name)
+	if err != nil {
+		return err
+	}
+
+	contents = a.normalise(contents)
+
+	if err := a.checkMarkers(name, contents); err != nil {
+		return err
+	}
 
 	// Does it already exist? in which case overwrite it
-	for i := range a.files {
-		if a.files[i].name == name {
-			a.files[i] = file{name: name, contents: fixNL(contents)}
-			return nil
-		}
+	if i, ok := a.find(name); ok {
+		a.files[i].contents = contents
+		return nil
 	}
 
 	// If not, create it and append it
-	a.files = append(a.files, file{name: name, contents: fixNL(contents)})
+	if a.index == nil {
+		a.index = make(map[string]int)
+	}
+
+	a.index[name] = len(a.files)
+	a.files = append(a.files, file{name: name, contents: contents})
 
 	return nil
 }
@@ -149,26 +192,31 @@ func (a *Archive) Read(name string) (value string, ok bool) {
 		return "", false
 	}
 
-	name = strings.TrimSpace(name)
-	for _, file := range a.files {
-		if file.name == name {
-			return file.contents, true
-		}
+	i, ok := a.find(a.key(name))
+	if !ok {
+		return "", false
 	}
 
-	return "", false
+	return a.files[i].contents, true
 }
 
 // Delete removes a file from the archive.
 //
-// If the file does not exist, Delete is a no-op.
+// If the file does not exist, Delete is a no-op. If the archive contains duplicates
+// of the file (see [DuplicateKeepAll]), they are all removed.
 func (a *Archive) Delete(name string) {
 	if a == nil {
 		return
 	}
 
-	name = strings.TrimSpace(name)
+	name = a.key(name)
+	if _, ok := a.find(name); !ok {
+		return
+	}
+
+	// Everything after it has moved so the index must be rebuilt
 	a.files = slices.DeleteFunc(a.files, func(f file) bool { return f.name == name })
+	a.reindex()
 }
 
 // Size returns the number of files stored in the archive.
@@ -183,37 +231,50 @@ func (a *Archive) Size() int {
 // String implements the [fmt.Stringer] interface for an [Archive], allowing
 // it to print itself.
 //
-// Files are printed in the order they were added to the archive.
+// Files are printed in archive order (the order they were added, unless moved since),
+// or sorted by name if the archive was created with [WithSorted].
 func (a *Archive) String() string {
 	if a == nil {
 		return ""
 	}
 
 	s := &strings.Builder{}
+	s.WriteString(a.header())
 
-	if a.comment != "" {
-		s.WriteString(a.comment)
-		s.WriteString("\n")
-
-		// If there are files after the comment we need an extra newline after the comment
-		if len(a.files) != 0 {
-			s.WriteByte('\n')
-		}
-	}
-
-	for _, file := range a.files {
+	for _, file := range a.ordered(a.sorted) {
 		s.WriteString("-- ")
 		s.WriteString(file.name)
 		s.WriteString(" --\n")
-		s.WriteString(file.contents)
+		s.WriteString(a.quote(file.contents))
 	}
 
 	return s.String()
 }
 
+// header returns the text String writes before the first file marker: the quoted
+// comment and any whitespace separating it from the files.
+func (a *Archive) header() string {
+	if a.comment == "" {
+		return ""
+	}
+
+	// In verbatim mode the comment already has any separating whitespace in it
+	if a.verbatim {
+		return a.quote(a.comment)
+	}
+
+	// If there are files after the comment we need an extra newline after the comment
+	if len(a.files) != 0 {
+		return a.quote(a.comment) + "\n\n"
+	}
+
+	return a.quote(a.comment) + "\n"
+}
+
 // Files returns an iterator over the archive's filenames and contents.
 //
-// Files are yielded in the order they were added to the archive.
+// Files are yielded in archive order, the order they were added unless moved since
+// e.g. by [Archive.Sort]. This is not affected by [WithSorted].
 func (a *Archive) Files() iter.Seq2[string, string] {
 	if a == nil {
 		return func(yield func(string, string) bool) {}
@@ -252,77 +313,246 @@ func New(options ...Option) (*Archive, error) {
 // the presence of a malformed document. We also take an [io.Reader] rather than
 // a byte slice for greater flexibility.
 //
-// For a shortcut to parse from a file see [ParseFile].
+// Errors caused by a malformed document are of type [*ParseError], describing
+// where in the document the problem was found.
+//
+// Parse is equivalent to calling [ParseWith] with no options, for a shortcut
+// to parse from a file see [ParseFile].
 func Parse(r io.Reader) (*Archive, error) {
-	data, err := io.ReadAll(r)
+	return ParseWith(r)
+}
+
+// ParseWith is like [Parse] but applies any number of options to configure the parser.
+//
+// For example, to parse documents the way the original txtar package does:
+//
+//	archive, err := txtar.ParseWith(r, txtar.Lenient())
+func ParseWith(r io.Reader, options ...ParseOption) (*Archive, error) {
+	return parse(r, "", options)
+}
+
+// ParseFile is a convenience wrapper around [Parse] when reading an
+// archive from a File.
+//
+// Any [*ParseError] returned will have its Name set to name.
+func ParseFile(name string) (*Archive, error) {
+	return ParseFileWith(name)
+}
+
+// ParseFileWith is a convenience wrapper around [ParseWith] when reading an
+// archive from a File.
+//
+// Any [*ParseError] returned will have its Name set to name.
+func ParseFileWith(name string, options ...ParseOption) (*Archive, error) {
+	file, err := os.Open(name)
 	if err != nil {
 		return nil, err
 	}
+	defer file.Close()
+
+	return parse(file, name, options)
+}
+
+// parse implements [ParseWith], name is the name of the source being parsed and
+// is used only for error reporting.
+func parse(r io.Reader, name string, options []ParseOption) (*Archive, error) {
+	cfg := parseConfig{}
 
-	if len(data) == 0 {
-		return nil, errors.New("Parse: cannot parse empty txtar archive")
+	// Like New, report all the option errors at once
+	var errs error
+	for _, option := range options {
+		errs = errors.Join(errs, option(&cfg))
 	}
 
-	if !bytes.Contains(data, marker) {
-		return nil, errors.New("Parse: archive contains no files")
+	if errs != nil {
+		return nil, errs
 	}
 
-	// Stupid windows
-	data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+	reader := NewReader(r)
 
-	archive := &Archive{}
+	// Everything before the first file is the comment, this is the only part of
+	// the archive we hold in memory in its raw form as we need it to report errors
+	comment, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
 
-	comment, name, data := findFileMarker(data)
-	if data == nil {
-		return nil, errors.New("Parse: unterminated file marker")
+	archive := &Archive{verbatim: cfg.verbatim, names: cfg.names}
+	if cfg.verbatim {
+		archive.comment = fixNL(string(comment))
+	} else {
+		archive.comment = string(bytes.TrimSpace(comment))
 	}
 
-	archive.comment = string(bytes.TrimSpace(comment))
+	if cfg.quoted {
+		archive.markers = MarkerQuote
+		archive.comment = unquote(archive.comment)
+	}
 
-	for name != "" {
-		fileName := name // Copy of the "before" filename
+	archive.index = make(map[string]int)
+	lines := make(map[string]int) // Line of each file's first marker, for reporting duplicates
 
-		var contents []byte
-		contents, name, data = findFileMarker(data)
-		archive.files = append(
-			archive.files,
-			file{name: fileName, contents: fixNL(strings.TrimSpace(string(contents)))},
-		)
-	}
+	// io.Copy would allocate a new buffer for every file, share one instead
+	buf := make([]byte, copyBufferSize)
 
-	return archive, nil
-}
+	for {
+		header, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
 
-// ParseFile is a convenience wrapper around [Parse] when reading an
-// archive from a File.
-func ParseFile(name string) (*Archive, error) {
-	file, err := os.Open(name)
-	if err != nil {
-		return nil, err
+		if err != nil {
+			return nil, err
+		}
+
+		fileName, err := archive.checkName(header.Name)
+		if err != nil {
+			return nil, &ParseError{
+				Name:   name,
+				Err:    err,
+				Marker: "-- " + header.Name + " --",
+				Line:   header.Line,
+				Col:    1,
+			}
+		}
+
+		first, duplicate := archive.index[fileName]
+		if duplicate {
+			switch cfg.duplicates {
+			case DuplicateError:
+				return nil, &ParseError{
+					Name:      name,
+					Err:       ErrDuplicateFile,
+					Marker:    "-- " + fileName + " --",
+					Line:      header.Line,
+					Col:       1,
+					FirstLine: lines[fileName],
+				}
+			case DuplicateKeepFirst:
+				// Next skips the rest of this file for us
+				continue
+			}
+		}
+
+		contents := &strings.Builder{}
+		if _, err := io.CopyBuffer(contents, reader, buf); err != nil {
+			return nil, err
+		}
+
+		text := contents.String()
+		if cfg.quoted {
+			text = unquote(text)
+		}
+
+		text = archive.normalise(text)
+
+		if duplicate && cfg.duplicates == DuplicateKeepLast {
+			archive.files[first].contents = text
+			continue
+		}
+
+		if !duplicate {
+			archive.index[fileName] = len(archive.files)
+			lines[fileName] = header.Line
+		}
+
+		archive.files = append(archive.files, file{name: fileName, contents: text})
 	}
-	defer file.Close()
 
-	return Parse(file)
+	// If we found no files at all then the entire document is in the comment,
+	// so it is either empty, entirely comment, or has something that looks like a
+	// marker but isn't quite right
+	if len(archive.files) == 0 && !cfg.lenient {
+		switch offset := bytes.Index(comment, marker); {
+		case len(comment) == 0:
+			return nil, &ParseError{Name: name, Err: ErrEmpty}
+		case offset < 0:
+			return nil, &ParseError{Name: name, Err: ErrNoFiles}
+		default:
+			// Point at the first "-- " as that's most likely to be the culprit
+			line, col := position(comment, offset)
+
+			return nil, &ParseError{
+				Name:   name,
+				Line:   line,
+				Col:    col,
+				Marker: lineAt(comment, offset),
+				Err:    ErrUnterminatedMarker,
+			}
+		}
+	}
+
+	return archive, nil
 }
 
-// Dump writes the [Archive] to w in its serialised representation.
-func Dump(w io.Writer, archive *Archive) error {
+// Dump writes the [Archive] to w in its serialised representation, applying
+// any number of [DumpOption].
+//
+// The archive is streamed to w using a [Writer] rather than being built up
+// in memory first.
+func Dump(w io.Writer, archive *Archive, options ...DumpOption) error {
 	if archive == nil {
 		return errors.New("Dump: archive was nil")
 	}
 
-	_, err := w.Write([]byte(archive.String()))
+	cfg := dumpConfig{}
+
+	var errs error
+	for _, option := range options {
+		errs = errors.Join(errs, option(&cfg))
+	}
+
+	if errs != nil {
+		return errs
+	}
+
+	buf := bufio.NewWriter(w)
+	writer := NewWriter(buf)
+	writer.Verbatim = archive.verbatim
+
+	if err := writer.WriteComment(archive.quote(archive.comment)); err != nil {
+		return err
+	}
+
+	for _, file := range archive.ordered(archive.sorted || cfg.sorted) {
+		contents, err := writer.CreateFile(file.name)
+		if err != nil {
+			return err
+		}
+
+		if _, err := io.WriteString(contents, archive.quote(file.contents)); err != nil {
+			return err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return err
+	}
 
-	return err
+	return buf.Flush()
 }
 
 // DumpFile is a convenience wrapper around [Dump] when serialising an
 // archive to a file.
 //
 // If the file does not exist, it is created.
-func DumpFile(name string, archive *Archive) error {
+func DumpFile(name string, archive *Archive, options ...DumpOption) (err error) {
 	const filePerms = 0o644
-	return os.WriteFile(name, []byte(archive.String()), filePerms)
+
+	if archive == nil {
+		return errors.New("DumpFile: archive was nil")
+	}
+
+	file, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, filePerms)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		err = errors.Join(err, file.Close())
+	}()
+
+	return Dump(file, archive, options...)
 }
 
 // Equal returns whether two archives should be considered equal.
@@ -330,7 +560,8 @@ func DumpFile(name string, archive *Archive) error {
 // An archive is considered equal to another if they have the same comment
 // and the same files, or they are both nil pointers.
 //
-// Otherwise they are considered not equal.
+// Otherwise they are considered not equal. Use [EqualWith] to configure the comparison,
+// e.g. to ignore the order of files, or [Diff] to find out how two archives differ.
 func Equal(a, b *Archive) bool {
 	// Mirroring the behaviour of maps.Equal, two nil maps report true
 	if (a == nil) && (b == nil) {
@@ -351,30 +582,38 @@ func Equal(a, b *Archive) bool {
 	return slices.Equal(a.files, b.files)
 }
 
-// Below is verbatim the parser from the original package, the only exception being we don't need fixNL
-// because we are stricter about how we store comment and file contents
+// normalise applies the archive's whitespace rules to the contents of a file.
+func (a *Archive) normalise(contents string) string {
+	if a.verbatim {
+		return fixNL(contents)
+	}
+
+	return fixNL(strings.TrimSpace(contents))
+}
 
-// findFileMarker finds the next file marker in data,
-// extracts the file name, and returns the data before the marker,
-// the file name, and the data after the marker.
-// If there is no next marker, findFileMarker returns before = fixNL(data), name = "", after = nil.
-func findFileMarker(data []byte) (before []byte, name string, after []byte) {
-	var i int
+// find returns the position in a.files of the file with the given (already normalised) name.
+//
+// The index is kept up to date by everything that modifies a.files.
+func (a *Archive) find(name string) (int, bool) {
+	i, ok := a.index[name]
 
-	for {
-		if name, after = isMarker(data[i:]); name != "" {
-			return data[:i], name, after
-		}
+	return i, ok
+}
 
-		j := bytes.Index(data[i:], newlineMarker)
-		if j < 0 {
-			return data, "", nil
+// reindex rebuilds the archive's name index from scratch, for use after files have
+// been removed or reordered.
+func (a *Archive) reindex() {
+	a.index = make(map[string]int, len(a.files))
+	for i, file := range a.files {
+		if _, ok := a.index[file.name]; !ok {
+			a.index[file.name] = i
 		}
-
-		i += j + 1 // positioned at start of new possible marker
 	}
 }
 
+// Below is adapted from the parser in the original package, the file marker
+// detection is identical but the scanning itself is done incrementally by [Reader].
+
 // isMarker checks whether data begins with a file marker line.
 // If so, it returns the name from the line and the data after the line.
 // Otherwise it returns name == "" with an unspecified after.
@@ -5,6 +5,8 @@ import (
 	"maps"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"testing"
 
@@ -677,6 +679,128 @@ func TestParseInvalid(t *testing.T) {
 	}
 }
 
+func TestParseError(t *testing.T) {
+	tests := []struct {
+		cause     error  // The expected sentinel cause
+		dir       string // Directory of the input file within testdata/TestParse, "invalid" if empty
+		name      string // Filename of the input file (relative to dir)
+		marker    string // Expected offending marker text
+		line      int    // Expected line number
+		col       int    // Expected column number
+		firstLine int    // Expected line of the first definition, for duplicates
+	}{
+		{
+			name:  "empty.txtar",
+			cause: txtar.ErrEmpty,
+		},
+		{
+			name:   "no_end_marker.txtar",
+			cause:  txtar.ErrUnterminatedMarker,
+			marker: "-- file1.txt",
+			line:   1,
+			col:    1,
+		},
+		{
+			name:  "no_files.txtar",
+			cause: txtar.ErrNoFiles,
+		},
+		{
+			name:  "no_start_marker.txtar",
+			cause: txtar.ErrNoFiles,
+		},
+		{
+			name:   "nonsense.txtar",
+			cause:  txtar.ErrUnterminatedMarker,
+			marker: "-- ",
+			line:   3,
+			col:    5,
+		},
+		{
+			dir:       "duplicate",
+			name:      "duplicate.txtar",
+			cause:     txtar.ErrDuplicateFile,
+			marker:    "-- a.txt --",
+			line:      7,
+			col:       1,
+			firstLine: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := tt.dir
+			if dir == "" {
+				dir = "invalid"
+			}
+
+			path := filepath.Join("testdata", "TestParse", dir, tt.name)
+
+			_, err := txtar.ParseFileWith(path, txtar.OnDuplicate(txtar.DuplicateError))
+			test.ErrorIs(t, err, tt.cause)
+
+			parseErr := test.ErrorAs[*txtar.ParseError](t, err)
+			test.Equal(t, parseErr.Name, path, test.Context("ParseFile should set the Name"))
+			test.Equal(t, parseErr.Line, tt.line, test.Context("Wrong line"))
+			test.Equal(t, parseErr.Col, tt.col, test.Context("Wrong column"))
+			test.Equal(t, parseErr.Marker, tt.marker, test.Context("Wrong marker"))
+			test.Equal(t, parseErr.FirstLine, tt.firstLine, test.Context("Wrong first line"))
+		})
+	}
+}
+
+func TestParseErrorString(t *testing.T) {
+	tests := []struct {
+		err  *txtar.ParseError // The error under test
+		name string            // Name of the test case
+		want string            // Expected output of Error()
+	}{
+		{
+			name: "cause only",
+			err:  &txtar.ParseError{Err: txtar.ErrNoFiles},
+			want: "Parse: archive contains no files",
+		},
+		{
+			name: "position only",
+			err:  &txtar.ParseError{Line: 2, Col: 4, Err: txtar.ErrUnterminatedMarker},
+			want: "Parse: 2:4: unterminated file marker",
+		},
+		{
+			name: "with name",
+			err:  &txtar.ParseError{Name: "test.txtar", Err: txtar.ErrEmpty},
+			want: "Parse: test.txtar: cannot parse empty txtar archive",
+		},
+		{
+			name: "with position",
+			err: &txtar.ParseError{
+				Name:   "test.txtar",
+				Line:   3,
+				Col:    1,
+				Marker: "-- file.txt",
+				Err:    txtar.ErrUnterminatedMarker,
+			},
+			want: `Parse: test.txtar:3:1: unterminated file marker "-- file.txt"`,
+		},
+		{
+			name: "duplicate",
+			err: &txtar.ParseError{
+				Name:      "test.txtar",
+				Line:      5,
+				Col:       1,
+				Marker:    "-- file.txt --",
+				FirstLine: 2,
+				Err:       txtar.ErrDuplicateFile,
+			},
+			want: `Parse: test.txtar:5:1: duplicate file name "-- file.txt --" (first defined on line 2)`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			test.Equal(t, tt.err.Error(), tt.want)
+		})
+	}
+}
+
 func TestParseStringRoundTrip(t *testing.T) {
 	pattern := filepath.Join("testdata", "TestParse", "valid", "*.txtar")
 	files, err := filepath.Glob(pattern)
@@ -720,6 +844,64 @@ func TestParseStringRoundTrip(t *testing.T) {
 	}
 }
 
+func TestParseVerbatimRoundTrip(t *testing.T) {
+	var files []string
+	for _, dir := range []string{"TestCompat", filepath.Join("TestParse", "valid")} {
+		matches, err := filepath.Glob(filepath.Join("testdata", dir, "*.txtar"))
+		test.Ok(t, err, test.Context("Could not glob the %s directory", dir))
+
+		files = append(files, matches...)
+	}
+
+	for _, file := range files {
+		t.Run(filepath.ToSlash(file), func(t *testing.T) {
+			contents, err := os.ReadFile(file)
+			test.Ok(t, err)
+
+			// Line endings are always normalised
+			contents = bytes.ReplaceAll(contents, []byte("\r\n"), []byte("\n"))
+
+			archive, err := txtar.ParseWith(bytes.NewReader(contents), txtar.Verbatim())
+			test.Ok(t, err)
+
+			// Should be byte-for-byte identical
+			test.Diff(t, archive.String(), string(contents))
+
+			buf := &bytes.Buffer{}
+			test.Ok(t, txtar.Dump(buf, archive))
+			test.Diff(t, buf.String(), string(contents))
+		})
+	}
+}
+
+func TestVerbatim(t *testing.T) {
+	archive, err := txtar.New(
+		txtar.WithVerbatim(),
+		txtar.WithComment("  An indented comment\n\n"),
+		txtar.WithFile("main.py", "    indented = True\n"),
+		txtar.WithFile("Makefile", "\n\n\techo hello"),
+	)
+	test.Ok(t, err)
+
+	test.Equal(t, archive.Comment(), "  An indented comment\n\n")
+
+	contents, ok := archive.Read("main.py")
+	test.True(t, ok)
+	test.Equal(t, contents, "    indented = True\n")
+
+	contents, ok = archive.Read("Makefile")
+	test.True(t, ok)
+	test.Equal(t, contents, "\n\n\techo hello\n", test.Context("Should only have added a final newline"))
+
+	want := "  An indented comment\n\n-- main.py --\n    indented = True\n-- Makefile --\n\n\n\techo hello\n"
+	test.Diff(t, archive.String(), want)
+
+	// Parsing it back should give the exact same archive
+	reparsed, err := txtar.ParseWith(strings.NewReader(archive.String()), txtar.Verbatim())
+	test.Ok(t, err)
+	test.True(t, txtar.Equal(archive, reparsed), test.Context("Verbatim archive did not survive a round trip"))
+}
+
 func TestFiles(t *testing.T) {
 	archive, err := txtar.New(
 		txtar.WithFile("file1", "some stuff"),
@@ -758,34 +940,202 @@ func TestCompat(t *testing.T) {
 			ourArchive, err := txtar.Parse(bytes.NewReader(contents))
 			test.Ok(t, err, test.Context("our txtar could not parse file"))
 
-			test.Equal(
-				t,
-				clean(string(goArchive.Comment)),
-				strings.TrimSpace(ourArchive.Comment()),
-				test.Context("Comment mismatch between x/tools/txtar and this package"),
-			)
+			compat(t, goArchive, ourArchive)
+		})
+	}
+}
 
-			test.Equal(
-				t,
-				len(goArchive.Files),
-				ourArchive.Size(),
-				test.Context("Mismatch in number of files"),
-			)
+func TestCompatLenient(t *testing.T) {
+	// In lenient mode we should accept everything x/tools/txtar does, including
+	// all the documents we'd normally reject as invalid
+	var files []string
+	for _, dir := range []string{"TestCompat", filepath.Join("TestParse", "valid"), filepath.Join("TestParse", "invalid"), filepath.Join("TestParse", "duplicate")} {
+		matches, err := filepath.Glob(filepath.Join("testdata", dir, "*.txtar"))
+		test.Ok(t, err, test.Context("Could not glob the %s directory", dir))
+
+		files = append(files, matches...)
+	}
+
+	for _, file := range files {
+		t.Run(filepath.ToSlash(file), func(t *testing.T) {
+			contents, err := os.ReadFile(file)
+			test.Ok(t, err)
+
+			// We need to normalise line endings to get equivalent behaviour on all platforms
+			contents = bytes.ReplaceAll(contents, []byte("\r\n"), []byte("\n"))
+
+			goArchive := gotxtar.Parse(contents)
+
+			ourArchive, err := txtar.ParseWith(bytes.NewReader(contents), txtar.Lenient())
+			test.Ok(t, err, test.Context("our txtar could not parse file in lenient mode"))
+
+			compat(t, goArchive, ourArchive)
+		})
+	}
+}
+
+func TestParseLenient(t *testing.T) {
+	tests := []struct {
+		name    string // Name of the test case
+		input   string // The txtar document to parse
+		comment string // Expected comment
+		files   int    // Expected number of files
+	}{
+		{
+			name:    "empty",
+			input:   "",
+			comment: "",
+			files:   0,
+		},
+		{
+			name:    "comment only",
+			input:   "Just a comment\n",
+			comment: "Just a comment",
+			files:   0,
+		},
+		{
+			name:    "unterminated marker",
+			input:   "-- file1.txt\nstuff\n",
+			comment: "-- file1.txt\nstuff",
+			files:   0,
+		},
+		{
+			name:    "marker without trailing newline",
+			input:   "comment\n-- file1.txt --",
+			comment: "comment",
+			files:   1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			archive, err := txtar.ParseWith(strings.NewReader(tt.input), txtar.Lenient())
+			test.Ok(t, err)
+
+			test.Equal(t, archive.Comment(), tt.comment)
+			test.Equal(t, archive.Size(), tt.files)
+		})
+	}
+}
+
+func TestParseDuplicates(t *testing.T) {
+	input := "A comment\n-- a.txt --\nfirst a\n-- b.txt --\nb\n-- a.txt --\nsecond a\n-- a.txt --\nthird a\n"
+
+	type file struct {
+		name     string
+		contents string
+	}
 
-			for _, file := range goArchive.Files {
-				test.True(t, ourArchive.Has(file.Name), test.Context("This package archive missing file"))
-				ourData, ok := ourArchive.Read(file.Name)
-				test.True(t, ok, test.Context("Could not read data"))
-
-				test.Equal(
-					t,
-					clean(ourData),
-					clean(string(file.Data)),
-					test.Context("File data mismatch"),
-				)
+	tests := []struct {
+		name       string              // Name of the test case
+		options    []txtar.ParseOption // Options to pass to ParseWith
+		files      []file              // Expected files in order
+		duplicates []file              // Expected files yielded by Duplicates
+	}{
+		{
+			name: "default keeps all",
+			files: []file{
+				{name: "a.txt", contents: "first a\n"},
+				{name: "b.txt", contents: "b\n"},
+				{name: "a.txt", contents: "second a\n"},
+				{name: "a.txt", contents: "third a\n"},
+			},
+			duplicates: []file{
+				{name: "a.txt", contents: "second a\n"},
+				{name: "a.txt", contents: "third a\n"},
+			},
+		},
+		{
+			name:    "keep first",
+			options: []txtar.ParseOption{txtar.OnDuplicate(txtar.DuplicateKeepFirst)},
+			files: []file{
+				{name: "a.txt", contents: "first a\n"},
+				{name: "b.txt", contents: "b\n"},
+			},
+		},
+		{
+			name:    "keep last",
+			options: []txtar.ParseOption{txtar.OnDuplicate(txtar.DuplicateKeepLast)},
+			files: []file{
+				{name: "a.txt", contents: "third a\n"},
+				{name: "b.txt", contents: "b\n"},
+			},
+		},
+		{
+			name:    "keep all",
+			options: []txtar.ParseOption{txtar.OnDuplicate(txtar.DuplicateKeepAll)},
+			files: []file{
+				{name: "a.txt", contents: "first a\n"},
+				{name: "b.txt", contents: "b\n"},
+				{name: "a.txt", contents: "second a\n"},
+				{name: "a.txt", contents: "third a\n"},
+			},
+			duplicates: []file{
+				{name: "a.txt", contents: "second a\n"},
+				{name: "a.txt", contents: "third a\n"},
+			},
+		},
+		{
+			name:    "lenient keeps all",
+			options: []txtar.ParseOption{txtar.Lenient()},
+			files: []file{
+				{name: "a.txt", contents: "first a\n"},
+				{name: "b.txt", contents: "b\n"},
+				{name: "a.txt", contents: "second a\n"},
+				{name: "a.txt", contents: "third a\n"},
+			},
+			duplicates: []file{
+				{name: "a.txt", contents: "second a\n"},
+				{name: "a.txt", contents: "third a\n"},
+			},
+		},
+		{
+			name:    "lenient with override",
+			options: []txtar.ParseOption{txtar.OnDuplicate(txtar.DuplicateKeepFirst), txtar.Lenient()},
+			files: []file{
+				{name: "a.txt", contents: "first a\n"},
+				{name: "b.txt", contents: "b\n"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			archive, err := txtar.ParseWith(strings.NewReader(input), tt.options...)
+			test.Ok(t, err)
+
+			var files []file
+			for name, contents := range archive.Files() {
+				files = append(files, file{name: name, contents: contents})
 			}
+
+			var duplicates []file
+			for name, contents := range archive.Duplicates() {
+				duplicates = append(duplicates, file{name: name, contents: contents})
+			}
+
+			test.EqualFunc(t, files, tt.files, slices.Equal)
+			test.EqualFunc(t, duplicates, tt.duplicates, slices.Equal)
+
+			// Read always gives the first one we kept
+			contents, ok := archive.Read("a.txt")
+			test.True(t, ok)
+			test.Equal(t, contents, tt.files[0].contents)
 		})
 	}
+
+	t.Run("error", func(t *testing.T) {
+		_, err := txtar.ParseWith(strings.NewReader(input), txtar.OnDuplicate(txtar.DuplicateError))
+		parseErr := test.ErrorAs[*txtar.ParseError](t, err)
+		test.ErrorIs(t, err, txtar.ErrDuplicateFile)
+		test.Equal(t, parseErr.Line, 6)
+		test.Equal(t, parseErr.FirstLine, 2)
+	})
+
+	t.Run("invalid policy", func(t *testing.T) {
+		_, err := txtar.ParseWith(strings.NewReader(input), txtar.OnDuplicate(42))
+		test.Err(t, err)
+	})
 }
 
 func TestDump(t *testing.T) {
@@ -836,6 +1186,47 @@ func TestDumpNilSafe(t *testing.T) {
 	test.Err(t, err)
 }
 
+// compat asserts that an archive parsed by x/tools/txtar and by this package are
+// equivalent.
+func compat(t *testing.T, goArchive *gotxtar.Archive, ourArchive *txtar.Archive) {
+	t.Helper()
+
+	test.Equal(
+		t,
+		clean(string(goArchive.Comment)),
+		strings.TrimSpace(ourArchive.Comment()),
+		test.Context("Comment mismatch between x/tools/txtar and this package"),
+	)
+
+	test.Equal(
+		t,
+		len(goArchive.Files),
+		ourArchive.Size(),
+		test.Context("Mismatch in number of files"),
+	)
+
+	// Compare in order rather than by name so that duplicate files are checked too
+	i := 0
+	for name, ourData := range ourArchive.Files() {
+		if i >= len(goArchive.Files) {
+			break
+		}
+
+		file := goArchive.Files[i]
+		i++
+
+		test.Equal(t, name, file.Name, test.Context("File name mismatch"))
+		test.True(t, ourArchive.Has(file.Name), test.Context("This package archive missing file"))
+
+		test.Equal(
+			t,
+			clean(ourData),
+			clean(string(file.Data)),
+			test.Context("File data mismatch"),
+		)
+	}
+}
+
 // clean de-windows's everything and trims all leading and trailing whitespace
 // returning a byte slice.
 func clean(data string) string {
@@ -843,3 +1234,80 @@ func clean(data string) string {
 
 	return strings.TrimSpace(data)
 }
+
+// benchSizes are the archive sizes (number of files) used in the benchmarks.
+var benchSizes = []int{10, 1000, 100_000}
+
+func BenchmarkWrite(b *testing.B) {
+	for _, size := range benchSizes {
+		names := benchNames(size)
+
+		b.Run(strconv.Itoa(size), func(b *testing.B) {
+			for b.Loop() {
+				archive, err := txtar.New()
+				test.Ok(b, err)
+
+				for _, name := range names {
+					test.Ok(b, archive.Write(name, "some contents"))
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkRead(b *testing.B) {
+	for _, size := range benchSizes {
+		names := benchNames(size)
+		archive := benchArchive(b, names)
+
+		b.Run(strconv.Itoa(size), func(b *testing.B) {
+			for b.Loop() {
+				for _, name := range names {
+					if _, ok := archive.Read(name); !ok {
+						b.Fatalf("missing file %s", name)
+					}
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkParse(b *testing.B) {
+	for _, size := range benchSizes {
+		archive := benchArchive(b, benchNames(size))
+		data := []byte(archive.String())
+
+		b.Run(strconv.Itoa(size), func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+
+			for b.Loop() {
+				_, err := txtar.Parse(bytes.NewReader(data))
+				test.Ok(b, err)
+			}
+		})
+	}
+}
+
+// benchNames returns size unique file names.
+func benchNames(size int) []string {
+	names := make([]string, 0, size)
+	for i := range size {
+		names = append(names, "dir/file"+strconv.Itoa(i)+".txt")
+	}
+
+	return names
+}
+
+// benchArchive returns an archive containing a file for each name.
+func benchArchive(b *testing.B, names []string) *txtar.Archive {
+	b.Helper()
+
+	archive, err := txtar.New(txtar.WithComment("A benchmark archive"))
+	test.Ok(b, err)
+
+	for _, name := range names {
+		test.Ok(b, archive.Write(name, "some contents\nspanning\nlines"))
+	}
+
+	return archive
+}
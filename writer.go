@@ -0,0 +1,309 @@
+package txtar
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Writer provides sequential, streaming serialisation of a txtar archive.
+//
+// It is the counterpart to [Reader] and works like [archive/zip.Writer]: call
+// [Writer.WriteComment] to (optionally) write the archive comment, then
+// [Writer.CreateFile] for each file, writing its contents to the returned [io.Writer].
+// Finally call [Writer.Close] to finish the last file.
+//
+// Data is written straight through to the underlying [io.Writer] with only
+// trailing whitespace buffered, so the output is never held in memory. Wrap
+// the destination in a [bufio.Writer] if many small writes are a concern.
+//
+// Comments, file names and contents are trimmed exactly as an [Archive] trims
+// them, so for the same inputs, the output is byte-for-byte identical to [Archive.String].
+//
+// Unlike an [Archive], a Writer cannot detect duplicate file names or file contents
+// containing lines that would be parsed as file markers (see [MarkerPolicy]).
+type Writer struct {
+	// Verbatim, if set, disables whitespace trimming so the comment and file contents are
+	// written as is, only adding a final newline if missing. This is the streaming equivalent of
+	// [WithVerbatim] and, like it, means no blank line is inserted after the comment.
+	//
+	// It must be set before anything is written.
+	Verbatim bool
+
+	w          io.Writer   // Where the archive is written to
+	err        error       // Sticky error
+	current    *fileWriter // The file currently being written, nil if none
+	hasComment bool        // Whether a non-empty comment has been written
+	hasFiles   bool        // Whether any files have been created
+	closed     bool        // Whether Close has been called
+}
+
+// NewWriter returns a new [Writer] writing a txtar archive to w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// WriteComment writes the top level archive comment.
+//
+// As with [WithComment], leading and trailing whitespace is trimmed unless the
+// Writer is in verbatim mode. WriteComment must
+// be called at most once and before any call to [Writer.CreateFile].
+func (w *Writer) WriteComment(comment string) error {
+	if w.err != nil {
+		return w.err
+	}
+
+	switch {
+	case w.closed:
+		return errors.New("WriteComment: Writer is closed")
+	case w.hasFiles:
+		return errors.New("WriteComment: comment must be written before any files")
+	case w.hasComment:
+		return errors.New("WriteComment: comment has already been written")
+	}
+
+	if w.Verbatim {
+		w.hasComment = true
+		return w.write(fixNL(comment))
+	}
+
+	comment = strings.TrimSpace(comment)
+	if comment == "" {
+		return nil
+	}
+
+	w.hasComment = true
+
+	return w.write(comment, "\n")
+}
+
+// CreateFile begins a new file in the archive with the given name, finishing off
+// any file previously being written.
+//
+// The file's contents should be written to the returned [io.Writer], which is only
+// valid until the next call to CreateFile or [Writer.Close]. Leading and trailing
+// whitespace in the contents is trimmed and a final newline added, exactly as
+// [Archive.Write] does.
+func (w *Writer) CreateFile(name string) (io.Writer, error) {
+	if w.err != nil {
+		return nil, w.err
+	}
+
+	if w.closed {
+		return nil, errors.New("CreateFile: Writer is closed")
+	}
+
+	if err := w.finish(); err != nil {
+		return nil, err
+	}
+
+	// If there are files after the comment we need an extra newline after the comment
+	if w.hasComment && !w.hasFiles && !w.Verbatim {
+		if err := w.write("\n"); err != nil {
+			return nil, err
+		}
+	}
+
+	w.hasFiles = true
+
+	if err := w.write("-- ", strings.TrimSpace(name), " --\n"); err != nil {
+		return nil, err
+	}
+
+	w.current = &fileWriter{w: w}
+
+	return w.current, nil
+}
+
+// Close finishes writing the archive, completing the final file.
+//
+// It does not close the underlying [io.Writer].
+func (w *Writer) Close() error {
+	if w.closed {
+		return w.err
+	}
+
+	w.closed = true
+
+	if w.err != nil {
+		return w.err
+	}
+
+	return w.finish()
+}
+
+// finish completes the file currently being written, if there is one.
+func (w *Writer) finish() error {
+	if w.current == nil {
+		return nil
+	}
+
+	file := w.current
+	w.current = nil
+
+	if w.Verbatim {
+		if file.needNL {
+			return w.write("\n")
+		}
+
+		return nil
+	}
+
+	// Whatever is left over is either trailing whitespace to be discarded or
+	// (at most) a final partial rune that turned out to be invalid and so counts as content
+	data := file.pending
+	if !file.started {
+		data, _ = trimLeadingSpace(data, true)
+	}
+
+	data = data[:trailingSpace(data, true)]
+
+	if _, err := w.w.Write(data); err != nil {
+		w.err = err
+		return err
+	}
+
+	if file.started || len(data) != 0 {
+		return w.write("\n")
+	}
+
+	return nil
+}
+
+// write writes each string to the underlying writer in turn.
+func (w *Writer) write(strs ...string) error {
+	for _, str := range strs {
+		if _, err := io.WriteString(w.w, str); err != nil {
+			w.err = err
+			return err
+		}
+	}
+
+	return nil
+}
+
+// fileWriter is the [io.Writer] returned from [Writer.CreateFile], it trims leading
+// and trailing whitespace from the file contents as they are written.
+type fileWriter struct {
+	w       *Writer // The parent Writer
+	pending []byte  // Possible trailing whitespace (or a partial rune) held back until we know more
+	started bool    // Whether any non-whitespace content has been seen
+	needNL  bool    // In verbatim mode, whether the contents so far are missing a final newline
+}
+
+// Write implements [io.Writer] for a fileWriter.
+func (f *fileWriter) Write(p []byte) (int, error) {
+	if f.w.err != nil {
+		return 0, f.w.err
+	}
+
+	if f.w.current != f {
+		return 0, errors.New("Write: file is no longer being written to")
+	}
+
+	n := len(p)
+
+	if f.w.Verbatim {
+		if n == 0 {
+			return 0, nil
+		}
+
+		if _, err := f.w.w.Write(p); err != nil {
+			f.w.err = err
+			return 0, err
+		}
+
+		f.needNL = p[n-1] != '\n'
+
+		return n, nil
+	}
+
+	buf := p
+	if len(f.pending) != 0 {
+		f.pending = append(f.pending, p...)
+		buf = f.pending
+	}
+
+	if !f.started {
+		var ok bool
+
+		buf, ok = trimLeadingSpace(buf, false)
+		if !ok {
+			// Nothing but whitespace so far (and maybe the start of a rune)
+			f.pending = append(f.pending[:0], buf...)
+			return n, nil
+		}
+
+		f.started = true
+	}
+
+	// Everything up to the last non-whitespace rune can go straight out, anything
+	// after it might end up being trailing whitespace so must be held back
+	end := trailingSpace(buf, false)
+
+	if _, err := f.w.w.Write(buf[:end]); err != nil {
+		f.w.err = err
+		return 0, err
+	}
+
+	f.pending = append(f.pending[:0], buf[end:]...)
+
+	return n, nil
+}
+
+// trimLeadingSpace trims leading whitespace (as defined by [strings.TrimSpace]) from data,
+// reporting whether it found the start of some non-whitespace content.
+//
+// If final is false, data is treated as possibly incomplete so a partial rune at the
+// end is left in place rather than treated as content.
+func trimLeadingSpace(data []byte, final bool) (rest []byte, ok bool) {
+	for len(data) != 0 {
+		if !final && !utf8.FullRune(data) {
+			return data, false
+		}
+
+		r, size := utf8.DecodeRune(data)
+		if !unicode.IsSpace(r) {
+			return data, true
+		}
+
+		data = data[size:]
+	}
+
+	return data, false
+}
+
+// trailingSpace returns the index in data at which any trailing whitespace (as
+// defined by [strings.TrimSpace]) begins.
+//
+// If final is false, data is treated as possibly incomplete so a partial rune at
+// the end is considered part of the trailing region as it may yet turn out to
+// be whitespace.
+func trailingSpace(data []byte, final bool) int {
+	end := len(data)
+
+	if !final {
+		for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+			if utf8.RuneStart(data[i]) {
+				if !utf8.FullRune(data[i:]) {
+					end = i
+				}
+
+				break
+			}
+		}
+	}
+
+	for end > 0 {
+		r, size := utf8.DecodeLastRune(data[:end])
+		if !unicode.IsSpace(r) {
+			break
+		}
+
+		end -= size
+	}
+
+	return end
+}
@@ -0,0 +1,215 @@
+package txtar_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"go.followtheprocess.codes/test"
+	"go.followtheprocess.codes/txtar"
+)
+
+func TestWriter(t *testing.T) {
+	type file struct {
+		name     string
+		contents string
+	}
+
+	tests := []struct {
+		name    string // Name of the test case
+		comment string // Comment to write
+		files   []file // Files to write, in order
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name:    "only comment",
+			comment: "  A comment\n\n",
+		},
+		{
+			name: "only single file",
+			files: []file{
+				{name: "file1.txt", contents: "file1 contents"},
+			},
+		},
+		{
+			name:    "file and comment",
+			comment: "A comment",
+			files: []file{
+				{name: "file1.txt", contents: "file1 contents"},
+			},
+		},
+		{
+			name:    "multiple files",
+			comment: "A slightly longer comment\n\nspanning several\nlines\n",
+			files: []file{
+				{name: "afile.txt", contents: "file1 contents"},
+				{name: " bfile.txt ", contents: "\n\n\tfile2 contents\n\n  \n"},
+				{name: "dir/file3.txt", contents: "  indented\n    more\n"},
+				{name: "empty.txt", contents: ""},
+				{name: "whitespace.txt", contents: " \n\t\r\n "},
+			},
+		},
+		{
+			name: "unicode whitespace",
+			files: []file{
+				{name: "nbsp.txt", contents: "  hello  world\u0085　"},
+				{name: "multibyte.txt", contents: "héllo wörld ✓"},
+			},
+		},
+		{
+			name: "invalid utf8",
+			files: []file{
+				{name: "invalid.txt", contents: "  \xff\xfe stuff \xc2"},
+				{name: "only invalid.txt", contents: "\xc2  "},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := []txtar.Option{txtar.WithComment(tt.comment)}
+			for _, file := range tt.files {
+				options = append(options, txtar.WithFile(file.name, file.contents))
+			}
+
+			archive, err := txtar.New(options...)
+			test.Ok(t, err)
+
+			// Write it in one go, and also one byte at a time to make sure
+			// whitespace that straddles writes is handled
+			for _, chunk := range []int{0, 1} {
+				buf := &bytes.Buffer{}
+				writer := txtar.NewWriter(buf)
+
+				test.Ok(t, writer.WriteComment(tt.comment))
+
+				for _, file := range tt.files {
+					w, err := writer.CreateFile(file.name)
+					test.Ok(t, err)
+
+					if chunk == 0 {
+						_, err = io.WriteString(w, file.contents)
+						test.Ok(t, err)
+
+						continue
+					}
+
+					for i := range len(file.contents) {
+						_, err = io.WriteString(w, file.contents[i:i+1])
+						test.Ok(t, err)
+					}
+				}
+
+				test.Ok(t, writer.Close())
+				test.Diff(t, buf.String(), archive.String())
+			}
+		})
+	}
+}
+
+func TestWriterVerbatim(t *testing.T) {
+	archive, err := txtar.New(
+		txtar.WithVerbatim(),
+		txtar.WithComment("  comment"),
+		txtar.WithFile("a.txt", "\n  leading"),
+		txtar.WithFile("b.txt", "trailing  \n\n"),
+		txtar.WithFile("empty.txt", ""),
+	)
+	test.Ok(t, err)
+
+	buf := &bytes.Buffer{}
+	writer := txtar.NewWriter(buf)
+	writer.Verbatim = true
+
+	test.Ok(t, writer.WriteComment("  comment"))
+
+	for name, contents := range archive.Files() {
+		w, err := writer.CreateFile(name)
+		test.Ok(t, err)
+
+		// Byte at a time so the final newline rule has to cope with split writes
+		for i := range len(contents) {
+			_, err = io.WriteString(w, contents[i:i+1])
+			test.Ok(t, err)
+		}
+	}
+
+	test.Ok(t, writer.Close())
+	test.Diff(t, buf.String(), archive.String())
+}
+
+func TestWriterMisuse(t *testing.T) {
+	t.Run("comment after files", func(t *testing.T) {
+		writer := txtar.NewWriter(io.Discard)
+		_, err := writer.CreateFile("file")
+		test.Ok(t, err)
+		test.Err(t, writer.WriteComment("too late"))
+	})
+
+	t.Run("comment twice", func(t *testing.T) {
+		writer := txtar.NewWriter(io.Discard)
+		test.Ok(t, writer.WriteComment("one"))
+		test.Err(t, writer.WriteComment("two"))
+	})
+
+	t.Run("create after close", func(t *testing.T) {
+		writer := txtar.NewWriter(io.Discard)
+		test.Ok(t, writer.Close())
+
+		_, err := writer.CreateFile("file")
+		test.Err(t, err)
+	})
+
+	t.Run("write to old file", func(t *testing.T) {
+		writer := txtar.NewWriter(io.Discard)
+		first, err := writer.CreateFile("first")
+		test.Ok(t, err)
+
+		_, err = writer.CreateFile("second")
+		test.Ok(t, err)
+
+		_, err = io.WriteString(first, "stuff")
+		test.Err(t, err)
+	})
+}
+
+func TestWriterError(t *testing.T) {
+	bang := errors.New("bang")
+	writer := txtar.NewWriter(errWriter{err: bang})
+
+	err := writer.WriteComment("comment")
+	test.ErrorIs(t, err, bang)
+
+	// Should be sticky
+	_, err = writer.CreateFile("file")
+	test.ErrorIs(t, err, bang)
+
+	test.ErrorIs(t, writer.Close(), bang)
+}
+
+func TestDumpMatchesString(t *testing.T) {
+	archive, err := txtar.New(
+		txtar.WithComment("A comment"),
+		txtar.WithFile("file1.txt", strings.Repeat("lots of stuff\n", 1000)),
+		txtar.WithFile("file2.txt", ""),
+		txtar.WithFile("file3.txt", "last"),
+	)
+	test.Ok(t, err)
+
+	buf := &bytes.Buffer{}
+	test.Ok(t, txtar.Dump(buf, archive))
+	test.Diff(t, buf.String(), archive.String())
+}
+
+// errWriter is an io.Writer that always fails.
+type errWriter struct {
+	err error
+}
+
+func (e errWriter) Write(p []byte) (int, error) {
+	return 0, e.err
+}
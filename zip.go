@@ -0,0 +1,130 @@
+package txtar
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// DumpZip writes the [Archive] to w as a zip file, applying any number of [DumpOption].
+//
+// Every file is deflated and given the same fixed headers as [DumpTar], in the order they
+// would be serialised by [Dump], so the output depends only on the archive. The archive
+// comment becomes the zip file comment.
+//
+// File names are validated and normalised with [ValidName] as zip files containing
+// absolute or escaping paths are a hazard to extract, an invalid name is an error.
+func DumpZip(w io.Writer, archive *Archive, options ...DumpOption) error {
+	if archive == nil {
+		return errors.New("DumpZip: archive was nil")
+	}
+
+	cfg := dumpConfig{}
+
+	var errs error
+	for _, option := range options {
+		errs = errors.Join(errs, option(&cfg))
+	}
+
+	if errs != nil {
+		return errs
+	}
+
+	zw := zip.NewWriter(w)
+
+	if err := zw.SetComment(archive.comment); err != nil {
+		return fmt.Errorf("DumpZip: %w", err)
+	}
+
+	for _, file := range archive.ordered(archive.sorted || cfg.sorted) {
+		name, err := ValidName(file.name)
+		if err != nil {
+			return fmt.Errorf("DumpZip: %w", err)
+		}
+
+		header := &zip.FileHeader{
+			Name:     name,
+			Method:   zip.Deflate,
+			Modified: memberTime,
+		}
+		header.SetMode(memberMode)
+
+		contents, err := zw.CreateHeader(header)
+		if err != nil {
+			return fmt.Errorf("DumpZip: %s: %w", name, err)
+		}
+
+		if _, err := io.WriteString(contents, file.contents); err != nil {
+			return fmt.Errorf("DumpZip: %s: %w", name, err)
+		}
+	}
+
+	return zw.Close()
+}
+
+// FromZip builds an [Archive] from the zip file of the given size read from r, applying
+// any number of options to control which files are included exactly as for [FromFS].
+//
+// Files are added in the order they appear in the zip file, everything other than
+// regular files is skipped and names are handled exactly as by [FromTar]. The zip file
+// comment becomes the archive comment.
+//
+// Like [FromFS], FromZip attempts every file and reports all the errors together,
+// except for errors reading the zip file's directory which stop it immediately.
+func FromZip(r io.ReaderAt, size int64, options ...FromOption) (*Archive, error) {
+	cfg := fromConfig{}
+
+	var errs error
+	for _, option := range options {
+		errs = errors.Join(errs, option(&cfg))
+	}
+
+	if errs != nil {
+		return nil, errs
+	}
+
+	zr, err := zip.NewReader(r, size)
+	if err != nil && !errors.Is(err, zip.ErrInsecurePath) {
+		// Insecure paths are checked by readMember like any other name
+		return nil, fmt.Errorf("FromZip: %w", err)
+	}
+
+	archive := &Archive{markers: cfg.markers}
+	errs = WithComment(zr.Comment)(archive)
+
+	for _, member := range zr.File {
+		if !member.Mode().IsRegular() {
+			continue
+		}
+
+		var rc io.ReadCloser
+
+		open := func() (io.Reader, error) {
+			f, err := member.Open()
+			rc = f
+
+			return f, err
+		}
+
+		name, contents, ok, err := readMember(member.Name, int64(member.UncompressedSize64), open, cfg)
+		if rc != nil {
+			errs = errors.Join(errs, rc.Close())
+		}
+
+		if err != nil {
+			errs = errors.Join(errs, fmt.Errorf("FromZip: %s: %w", member.Name, err))
+			continue
+		}
+
+		if ok {
+			errs = errors.Join(errs, archive.Write(name, contents))
+		}
+	}
+
+	if errs != nil {
+		return nil, errs
+	}
+
+	return archive, nil
+}
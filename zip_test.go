@@ -0,0 +1,85 @@
+package txtar_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"slices"
+	"testing"
+	"time"
+
+	"go.followtheprocess.codes/test"
+	"go.followtheprocess.codes/txtar"
+)
+
+func TestZipRoundTrip(t *testing.T) {
+	archive, err := txtar.New(
+		txtar.WithComment("A comment\nover lines"),
+		txtar.WithFile("z.txt", "z"),
+		txtar.WithFile("dir/a.txt", "a"),
+		txtar.WithFile("empty.txt", ""),
+	)
+	test.Ok(t, err)
+
+	buf := &bytes.Buffer{}
+	test.Ok(t, txtar.DumpZip(buf, archive))
+
+	got, err := txtar.FromZip(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	test.Ok(t, err)
+	test.True(t, txtar.Equal(got, archive), test.Context("Mismatch: %v", txtar.Mismatch(got, archive)))
+
+	// Reproducible, with fixed headers
+	again := &bytes.Buffer{}
+	test.Ok(t, txtar.DumpZip(again, archive))
+	test.True(t, bytes.Equal(buf.Bytes(), again.Bytes()), test.Context("DumpZip output is not reproducible"))
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	test.Ok(t, err)
+
+	for _, member := range zr.File {
+		test.Equal(t, member.Mode(), 0o644)
+		test.True(t, member.Modified.Equal(time.Date(1980, time.January, 1, 0, 0, 0, 0, time.UTC)))
+	}
+
+	test.Err(t, txtar.DumpZip(io.Discard, nil))
+}
+
+func TestFromZip(t *testing.T) {
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	test.Ok(t, zw.SetComment("zip comment"))
+
+	members := []struct {
+		name     string // Name of the member
+		contents string // Its contents
+	}{
+		{name: "dir/"},
+		{name: "b.txt", contents: "b"},
+		{name: "dir/a.go", contents: "package a"},
+		{name: "testdata/skip.txt", contents: "skip"},
+		{name: "image.png", contents: "\x89PNG\x00\x00"},
+	}
+
+	for _, member := range members {
+		w, err := zw.Create(member.name)
+		test.Ok(t, err)
+
+		_, err = io.WriteString(w, member.contents)
+		test.Ok(t, err)
+	}
+
+	test.Ok(t, zw.Close())
+
+	r := bytes.NewReader(buf.Bytes())
+
+	_, err := txtar.FromZip(r, r.Size())
+	test.ErrorIs(t, err, txtar.ErrBinaryFile)
+
+	archive, err := txtar.FromZip(r, r.Size(), txtar.WithBinary(txtar.BinarySkip), txtar.WithExclude("testdata"))
+	test.Ok(t, err)
+	test.Equal(t, archive.Comment(), "zip comment")
+	test.EqualFunc(t, names(archive), []string{"b.txt", "dir/a.go"}, slices.Equal)
+
+	_, err = txtar.FromZip(bytes.NewReader([]byte("not a zip")), 9)
+	test.Err(t, err)
+}